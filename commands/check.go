@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gruntwork-io/go-commons/errors"
+	"github.com/gruntwork-io/health-checker/server"
+	"github.com/urfave/cli/v3"
+)
+
+// Exit codes of the check subcommand. Without --nagios, only EXIT_CODE_OK and EXIT_CODE_WARNING are used, the latter
+// meaning that a critical check failed, which is what Docker HEALTHCHECK and systemd expect.
+const EXIT_CODE_OK = 0
+const EXIT_CODE_WARNING = 1
+const EXIT_CODE_CRITICAL = 2
+const EXIT_CODE_UNKNOWN = 3
+
+const OUTPUT_FORMAT_TEXT = "text"
+const OUTPUT_FORMAT_JSON = "json"
+
+var outputFlag = &cli.StringFlag{
+	Name:  "output",
+	Usage: fmt.Sprintf("[Optional] The format of the summary printed to stdout. Must be one of: %s, %s", OUTPUT_FORMAT_TEXT, OUTPUT_FORMAT_JSON),
+	Value: OUTPUT_FORMAT_TEXT,
+}
+
+var nagiosFlag = &cli.BoolFlag{
+	Name:  "nagios",
+	Usage: "[Optional] Use Nagios plugin exit codes: 0 if all checks passed, 1 if only warning checks failed, 2 if a critical check failed and 3 if the checks could not be run.",
+}
+
+var checkCommandFlags = []cli.Flag{
+	outputFlag,
+	nagiosFlag,
+}
+
+// createCheckCommand returns the check subcommand, which runs the configured checks once and exits instead of starting
+// the HTTP server. It accepts the same flags and config file as the server.
+func createCheckCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "check",
+		Usage:  "Run the configured checks once, print a summary and exit with a status code reflecting the result.",
+		Flags:  copyFlags(checkCommandFlags),
+		Action: runCheckOnce,
+	}
+}
+
+func runCheckOnce(ctx context.Context, cmd *cli.Command) error {
+	nagios := cmd.Bool(nagiosFlag.Name)
+
+	output := cmd.String(outputFlag.Name)
+	if output != OUTPUT_FORMAT_TEXT && output != OUTPUT_FORMAT_JSON {
+		return checkSetupError(InvalidOutputFormat(output), nagios)
+	}
+
+	opts, err := parseOptions(cmd)
+	if err != nil {
+		return checkSetupError(err, nagios)
+	}
+	// Keep stdout for the summary, so that it can be parsed when printed as JSON
+	opts.Logger.Out = os.Stderr
+
+	report := server.RunChecksOnce(opts)
+
+	if output == OUTPUT_FORMAT_JSON {
+		err = writeJsonReport(cmd.Root().Writer, report)
+	} else {
+		err = writeTextReport(cmd.Root().Writer, report)
+	}
+	if err != nil {
+		return checkSetupError(err, nagios)
+	}
+
+	switch {
+	case report.Failed && nagios:
+		return cli.Exit(report.Status, EXIT_CODE_CRITICAL)
+	case report.Failed:
+		return cli.Exit(report.Status, EXIT_CODE_WARNING)
+	case report.Degraded && nagios:
+		return cli.Exit("At least one non-critical health check failed", EXIT_CODE_WARNING)
+	default:
+		return nil
+	}
+}
+
+// checkSetupError wraps an error that prevented the checks from running at all. Nagios reserves a dedicated exit code
+// for this case.
+func checkSetupError(err error, nagios bool) error {
+	if nagios {
+		return cli.Exit(err, EXIT_CODE_UNKNOWN)
+	}
+	return errors.WithStackTrace(err)
+}
+
+func writeJsonReport(w io.Writer, report server.Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report.DetailedResponse)
+}
+
+// writeTextReport prints a one-line summary in the style of a Nagios plugin, followed by one line per check.
+func writeTextReport(w io.Writer, report server.Report) error {
+	label := "OK"
+	if report.Failed {
+		label = "CRITICAL"
+	} else if report.Degraded {
+		label = "WARNING"
+	}
+
+	passing := 0
+	for _, result := range report.Checks {
+		if result.Status == server.CheckStatusPassing {
+			passing++
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%s - %s (%d of %d checks passing in %s)\n", label, report.Status, passing, len(report.Checks), report.ElapsedTime)
+	if err != nil {
+		return err
+	}
+
+	for _, result := range report.Checks {
+		line := fmt.Sprintf("[%s] %s (%s, %s, %s)", result.Status, result.Name, result.Type, result.Severity, result.Duration)
+		if result.Error != "" {
+			line = fmt.Sprintf("%s: %s", line, result.Error)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type InvalidOutputFormat string
+
+func (format InvalidOutputFormat) Error() string {
+	return fmt.Sprintf("The --%s value \"%s\" is invalid, must be one of: %s, %s", outputFlag.Name, string(format), OUTPUT_FORMAT_TEXT, OUTPUT_FORMAT_JSON)
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/server"
+	"github.com/gruntwork-io/health-checker/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v3"
+)
+
+func TestCheckCommand(t *testing.T) {
+	ports, err := test.GetFreePorts(2)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+
+	// Only listen on the first port, so that the check against the second one fails
+	l, err := net.Listen("tcp", test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0]))
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	defer func() {
+		_ = l.Close()
+	}()
+
+	up := fmt.Sprintf("%d", ports[0])
+	down := fmt.Sprintf("%d", ports[1])
+
+	degradedConfigFile := filepath.Join(t.TempDir(), "degraded.yaml")
+	err = os.WriteFile(degradedConfigFile, []byte(fmt.Sprintf(`
+checks:
+  - {name: app, type: tcp, address: "%s"}
+  - {name: cache, type: tcp, address: "%s", severity: warning}
+`, up, down)), 0644)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name             string
+		args             []string
+		expectedExitCode int
+		expectedOutput   string
+	}{
+		{"passing", []string{"--port", up}, EXIT_CODE_OK, "OK - OK (1 of 1 checks passing"},
+		{"failing", []string{"--port", up, "--port", down, "--run-all"}, EXIT_CODE_WARNING, "CRITICAL - At least one health check failed (1 of 2 checks passing"},
+		{"degraded", []string{"--config", degradedConfigFile}, EXIT_CODE_OK, "WARNING - degraded (1 of 2 checks passing"},
+		{"nagios passing", []string{"--port", up, "--nagios"}, EXIT_CODE_OK, "OK - OK"},
+		{"nagios failing", []string{"--port", down, "--nagios"}, EXIT_CODE_CRITICAL, "[failing] " + down + " (tcp, critical"},
+		{"nagios degraded", []string{"--config", degradedConfigFile, "--nagios"}, EXIT_CODE_WARNING, "[failing] cache (tcp, warning"},
+		{"nagios setup error", []string{"--nagios"}, EXIT_CODE_UNKNOWN, ""},
+		{"invalid output format", []string{"--port", up, "--output", "xml", "--nagios"}, EXIT_CODE_UNKNOWN, ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			exitCode, output := runCheckCommandForTest(t, testCase.args)
+			assert.Equal(t, testCase.expectedExitCode, exitCode)
+			assert.Contains(t, output, testCase.expectedOutput)
+		})
+	}
+}
+
+func TestCheckCommandJsonOutput(t *testing.T) {
+	ports, err := test.GetFreePorts(1)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+
+	down := fmt.Sprintf("%d", ports[0])
+	exitCode, output := runCheckCommandForTest(t, []string{"--port", down, "--output", "json"})
+	assert.Equal(t, EXIT_CODE_WARNING, exitCode)
+
+	var detailed server.DetailedResponse
+	err = json.Unmarshal([]byte(output), &detailed)
+	assert.NoError(t, err)
+	assert.Equal(t, "At least one health check failed", detailed.Status)
+	if assert.Len(t, detailed.Checks, 1) {
+		assert.Equal(t, down, detailed.Checks[0].Name)
+		assert.Equal(t, server.CheckStatusFailing, detailed.Checks[0].Status)
+	}
+}
+
+// runCheckCommandForTest runs the check subcommand with the given args, returning the exit code it would have exited
+// with and what it printed to stdout.
+func runCheckCommandForTest(t *testing.T, args []string) (int, string) {
+	var output bytes.Buffer
+	exitCode := EXIT_CODE_OK
+
+	app := CreateCli("0.0.0")
+	app.Writer = &output
+	app.ExitErrHandler = func(ctx context.Context, cmd *cli.Command, err error) {
+		if exitErr, ok := err.(cli.ExitCoder); ok {
+			exitCode = exitErr.ExitCode()
+		}
+	}
+
+	err := app.Run(context.Background(), append([]string{"health-checker", "check"}, args...))
+	if err != nil && exitCode == EXIT_CODE_OK {
+		// Errors that are not exit codes make main exit with 1
+		exitCode = 1
+	}
+	return exitCode, output.String()
+}
@@ -2,6 +2,9 @@ package commands
 
 import (
 	"context"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gruntwork-io/go-commons/errors"
 	"github.com/gruntwork-io/health-checker/server"
@@ -37,8 +40,8 @@ func CreateCli(version string) *cli.Command {
 	//	app.Author = "Gruntwork, Inc. <www.gruntwork.io> | https://github.com/gruntwork-io/health-checker"
 	app.Version = version
 	app.Usage = "A simple HTTP server that will return 200 OK if the configured checks are all successful."
-	app.Commands = nil
-	app.Flags = defaultFlags
+	app.Commands = []*cli.Command{createCheckCommand()}
+	app.Flags = copyFlags(defaultFlags)
 	app.Action = runHealthChecker
 
 	return app
@@ -70,8 +73,38 @@ func runHealthChecker(ctx context.Context, cmd *cli.Command) error {
 		}
 		opts.Logger.Infof("The Health Check will attempt to connect to the following URLs via HTTP/S: %v", urls)
 	}
+	if len(opts.DnsChecks) > 0 {
+		var hosts []string
+		for _, check := range opts.DnsChecks {
+			hosts = append(hosts, check.Host)
+		}
+		opts.Logger.Infof("The Health Check will attempt to resolve the following names via DNS: %v", hosts)
+	}
+	if len(opts.TlsChecks) > 0 {
+		var labels []string
+		for _, check := range opts.TlsChecks {
+			labels = append(labels, check.Label())
+		}
+		opts.Logger.Infof("The Health Check will attempt to verify the following TLS certificates: %v", labels)
+	}
+	if len(opts.UdpChecks) > 0 {
+		var addresses []string
+		for _, check := range opts.UdpChecks {
+			addresses = append(addresses, check.Address)
+		}
+		opts.Logger.Infof("The Health Check will attempt to send datagrams to the following addresses via UDP: %v", addresses)
+	}
+	// Shut down gracefully on the first signal. Once it was received, the default handling is restored, so that a
+	// second signal terminates the process immediately
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	opts.Logger.Infof("Listening on Port %s...", opts.Listener)
-	err = server.StartHttpServer(opts)
+	err = server.StartHttpServerWithContext(ctx, opts)
 	if err != nil {
 		return errors.WithStackTrace(err)
 	}
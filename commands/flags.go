@@ -14,7 +14,15 @@ import (
 const DEFAULT_LISTENER_IP_ADDRESS = "0.0.0.0"
 const DEFAULT_LISTENER_PORT = 5500
 const DEFAULT_SCRIPT_TIMEOUT_SEC = 5
+const DEFAULT_CHECK_INTERVAL_SEC = 10
+const DEFAULT_CHECK_JITTER_SEC = 1
 const ENV_VAR_NAME_DEBUG_MODE = "HEALTH_CHECKER_DEBUG"
+const ENV_VAR_NAME_AUTH_TOKEN = "HEALTH_CHECKER_AUTH_TOKEN"
+
+var configFlag = &cli.StringFlag{
+	Name:  "config",
+	Usage: "[Optional] Path to a YAML or JSON file declaring named checks, listener settings and timeouts. Command-line flags take precedence over values from the file, and checks passed as flags are added to the ones declared in the file. Example: /etc/health-checker/config.yaml",
+}
 
 var portFlag = &cli.StringSliceFlag{
 	Name:  "port",
@@ -55,7 +63,7 @@ var httpReadTimeoutFlag = &cli.IntFlag{
 
 var httpWriteTimeoutFlag = &cli.IntFlag{
 	Name:  "http-write-timeout",
-	Usage: "[Optional] Timeout, in seconds, for writing the HTTP response. Dynamically scales with script timeout + 5 if set to 0. Example: 15",
+	Usage: "[Optional] Timeout, in seconds, for writing the HTTP response. Dynamically scales with the largest effective check timeout + 5 if set to 0. Example: 15",
 	Value: 0,
 }
 
@@ -82,17 +90,104 @@ var singleflightFlag = &cli.BoolFlag{
 	Usage: "[Optional] Enable singleflight mode, which makes concurrent requests share the same check.",
 }
 
+var backgroundFlag = &cli.BoolFlag{
+	Name:  "background",
+	Usage: "[Optional] Run every check on its own interval in the background and answer inbound requests instantly from the latest results, instead of running the checks on every request.",
+}
+
+var checkIntervalFlag = &cli.IntFlag{
+	Name:  "check-interval",
+	Usage: "[Optional] Interval, in seconds, between two runs of the same check in background mode. Example: 10",
+	Value: DEFAULT_CHECK_INTERVAL_SEC,
+}
+
+var checkJitterFlag = &cli.IntFlag{
+	Name:  "check-jitter",
+	Usage: "[Optional] Maximum random delay, in seconds, added to every check interval in background mode so that checks don't all fire at once. Example: 1",
+	Value: DEFAULT_CHECK_JITTER_SEC,
+}
+
+var runAllFlag = &cli.BoolFlag{
+	Name:  "run-all",
+	Usage: "[Optional] Disable early short-circuiting, so that every check runs to completion and all failures are reported, instead of aborting the remaining checks on the first failure.",
+}
+
+var degradedStatusCodeFlag = &cli.IntFlag{
+	Name:  "degraded-status-code",
+	Usage: "[Optional] The HTTP status code returned when only non-critical (warning severity) checks fail. Example: 207",
+	Value: options.DefaultDegradedStatusCode,
+}
+
 var detailedStatusFlag = &cli.BoolFlag{
 	Name:  "detailed-status",
 	Usage: "[Optional] Return a detailed JSON payload indicating elapsed time and specific error messages if probes fail.",
 }
 
+var metricsFlag = &cli.BoolFlag{
+	Name:  "metrics",
+	Usage: fmt.Sprintf("[Optional] Serve Prometheus metrics about the checks and inbound requests on %s. When authentication is configured, scrapes are authorized like for the detailed status.", options.MetricsPath),
+}
+
+var authTokenFlag = &cli.StringSliceFlag{
+	Name:    "auth-token",
+	Usage:   "[Optional] A bearer token that authorizes requests to see the --detailed-status output and scrape --metrics. Requests that are not authorized get the plain status instead. Specify one or more times, or set the HEALTH_CHECKER_AUTH_TOKEN environment variable to keep the token out of the process list.",
+	Sources: cli.EnvVars(ENV_VAR_NAME_AUTH_TOKEN),
+}
+
+var authAllowCidrFlag = &cli.StringSliceFlag{
+	Name:  "auth-allow-cidr",
+	Usage: "[Optional] A source IP range, or single IP, that is authorized to see the --detailed-status output and scrape --metrics without credentials. Specify one or more times. Example: 10.0.0.0/8",
+}
+
+var maintenanceFileFlag = &cli.StringFlag{
+	Name:  "maintenance-file",
+	Usage: "[Optional] Path to a sentinel file. While it exists, the instance is in maintenance mode and its readiness endpoints fail regardless of the checks. Example: /etc/health-checker/maintenance",
+}
+
+var maintenanceStatusCodeFlag = &cli.IntFlag{
+	Name:  "maintenance-status-code",
+	Usage: "[Optional] The HTTP status code returned by readiness endpoints in maintenance mode. Example: 503",
+	Value: options.DefaultMaintenanceStatusCode,
+}
+
+var maintenanceMessageFlag = &cli.StringFlag{
+	Name:  "maintenance-message",
+	Usage: "[Optional] The body returned by readiness endpoints in maintenance mode. Example: \"down for patching\"",
+	Value: options.DefaultMaintenanceMessage,
+}
+
+var adminFlag = &cli.BoolFlag{
+	Name:  "admin",
+	Usage: fmt.Sprintf("[Optional] Serve the admin endpoint %s, which turns maintenance mode on (POST) and off (DELETE). Requests are authorized like for the detailed status, so --auth-token or --auth-allow-cidr is required.", options.AdminMaintenancePath),
+}
+
 var listenerFlag = &cli.StringFlag{
 	Name:  "listener",
 	Usage: "[Optional] The IP address and port on which inbound HTTP connections will be accepted.",
 	Value: fmt.Sprintf("%s:%d", DEFAULT_LISTENER_IP_ADDRESS, DEFAULT_LISTENER_PORT),
 }
 
+var drainPeriodFlag = &cli.IntFlag{
+	Name:  "drain-period",
+	Usage: "[Optional] Time, in seconds, during which readiness endpoints fail before the listener is closed on SIGTERM or SIGINT, so that load balancers deregister the instance first. Example: 30",
+	Value: 0,
+}
+
+var tlsCertFlag = &cli.StringFlag{
+	Name:  "tls-cert",
+	Usage: "[Optional] Path to a PEM encoded certificate (chain) for the listener. Serves HTTPS instead of HTTP when set together with --tls-key. The file is reloaded when it changes. Example: /etc/health-checker/tls.crt",
+}
+
+var tlsKeyFlag = &cli.StringFlag{
+	Name:  "tls-key",
+	Usage: "[Optional] Path to the PEM encoded private key of --tls-cert. The file is reloaded when it changes. Example: /etc/health-checker/tls.key",
+}
+
+var tlsClientCaFlag = &cli.StringFlag{
+	Name:  "tls-client-ca",
+	Usage: "[Optional] Path to a PEM encoded CA bundle. When set, clients must present a certificate signed by one of these CAs. Requires --tls-cert. The file is reloaded when it changes. Example: /etc/health-checker/client-ca.crt",
+}
+
 var logLevelFlag = &cli.StringFlag{
 	Name:  "log-level",
 	Usage: fmt.Sprintf("[Optional] Set the log level to `LEVEL`. Must be one of: %v", logrus.AllLevels),
@@ -100,6 +195,7 @@ var logLevelFlag = &cli.StringFlag{
 }
 
 var defaultFlags = []cli.Flag{
+	configFlag,
 	portFlag,
 	scriptFlag,
 	httpCheckFlag,
@@ -107,16 +203,57 @@ var defaultFlags = []cli.Flag{
 	allowInsecureTlsFlag,
 	scriptTimeoutFlag,
 	detailedStatusFlag,
+	authTokenFlag,
+	authAllowCidrFlag,
+	degradedStatusCodeFlag,
 	httpReadTimeoutFlag,
 	httpWriteTimeoutFlag,
 	httpIdleTimeoutFlag,
 	tcpDialTimeoutFlag,
 	httpDialTimeoutFlag,
 	singleflightFlag,
+	backgroundFlag,
+	checkIntervalFlag,
+	checkJitterFlag,
+	runAllFlag,
+	metricsFlag,
+	maintenanceFileFlag,
+	maintenanceStatusCodeFlag,
+	maintenanceMessageFlag,
+	adminFlag,
 	listenerFlag,
+	drainPeriodFlag,
+	tlsCertFlag,
+	tlsKeyFlag,
+	tlsClientCaFlag,
 	logLevelFlag,
 }
 
+// copyFlags returns fresh copies of the given flag definitions. urfave/cli keeps parse state (such as whether a flag
+// was explicitly set) on the flag values themselves, so every Command needs its own copies for IsSet to be reliable.
+func copyFlags(flags []cli.Flag) []cli.Flag {
+	copies := make([]cli.Flag, 0, len(flags))
+	for _, flag := range flags {
+		switch f := flag.(type) {
+		case *cli.StringFlag:
+			c := *f
+			copies = append(copies, &c)
+		case *cli.StringSliceFlag:
+			c := *f
+			copies = append(copies, &c)
+		case *cli.IntFlag:
+			c := *f
+			copies = append(copies, &c)
+		case *cli.BoolFlag:
+			c := *f
+			copies = append(copies, &c)
+		default:
+			copies = append(copies, flag)
+		}
+	}
+	return copies
+}
+
 // Return true if no options at all were passed to the CLI. Note that we are specifically testing for flags, some of which
 // are required, not just args.
 func allCliOptionsEmpty(cmd *cli.Command) bool {
@@ -126,27 +263,43 @@ func allCliOptionsEmpty(cmd *cli.Command) bool {
 // parseOptions processes the user-provided CLI arguments from the urfave/cli/v3 Context.
 // It maps these inputs to the internal Options struct, configuring loggers, translating
 // string slices into domain objects (like Scripts), and validating that at least one
-// check strategy (port or script) was requested.
+// check strategy (port or script) was requested. If a config file was passed, its values
+// are used wherever the corresponding flag was not explicitly set.
 func parseOptions(cmd *cli.Command) (*options.Options, error) {
+	config := &options.Config{}
+	if configPath := cmd.String(configFlag.Name); configPath != "" {
+		loaded, err := options.LoadConfig(configPath)
+		if err != nil {
+			return nil, err
+		}
+		config = loaded
+	}
+
 	logger := logging.GetLogger("health-checker", "v0.0.0")
 
 	// By default logrus logs to stderr. But since most output in this tool is informational, we default to stdout.
 	logger.Logger.Out = os.Stdout
 
-	logLevel := cmd.String(logLevelFlag.Name)
+	logLevel := stringOption(cmd, logLevelFlag, config.LogLevel)
 	level, err := logrus.ParseLevel(logLevel)
 	if err != nil {
 		return nil, InvalidLogLevel(logLevel)
 	}
 	logger.Logger.SetLevel(level)
 
-	ports := cmd.StringSlice("port")
+	configPorts, configScripts, configHttpChecks, err := config.BuildChecks()
+	if err != nil {
+		return nil, err
+	}
+
+	ports := mergeChecks(configPorts, options.ParsePorts(cmd.StringSlice("port")))
 
 	scriptArr := cmd.StringSlice("script")
 	scripts, err := options.ParseScripts(scriptArr)
 	if err != nil {
 		return nil, err
 	}
+	scripts = mergeChecks(configScripts, scripts)
 
 	httpArr := cmd.StringSlice("http")
 	verifyPayloads := cmd.StringSlice("verify-payload")
@@ -166,31 +319,120 @@ func parseOptions(cmd *cli.Command) (*options.Options, error) {
 			VerifyPayload: verifyPayload,
 		})
 	}
+	httpChecks = mergeChecks(configHttpChecks, httpChecks)
 
-	if len(ports) == 0 && len(scripts) == 0 && len(httpChecks) == 0 {
+	// Other check types can only be declared in the configuration file
+	dnsChecks := config.BuildDnsChecks()
+	tlsChecks := config.BuildTlsChecks()
+	udpChecks := config.BuildUdpChecks()
+
+	if len(ports) == 0 && len(scripts) == 0 && len(httpChecks) == 0 && len(dnsChecks) == 0 && len(tlsChecks) == 0 && len(udpChecks) == 0 {
 		return nil, OneOfParamsRequired{portFlag.Name, scriptFlag.Name, httpCheckFlag.Name}
 	}
 
-	singleflight := cmd.Bool("singleflight")
-	detailedStatus := cmd.Bool("detailed-status")
-	allowInsecureTls := cmd.Bool("allow-insecure-tls")
+	seen := map[string]bool{}
+	for _, label := range (&options.Options{Ports: ports, Scripts: scripts, HttpChecks: httpChecks, DnsChecks: dnsChecks, TlsChecks: tlsChecks, UdpChecks: udpChecks}).CheckLabels() {
+		if seen[label] {
+			return nil, DuplicateCheckName(label)
+		}
+		seen[label] = true
+	}
+
+	singleflight := boolOption(cmd, singleflightFlag, config.Singleflight)
+	runAll := boolOption(cmd, runAllFlag, config.RunAll)
+	background := boolOption(cmd, backgroundFlag, config.Scheduler.Enabled)
+	checkInterval := intOption(cmd, checkIntervalFlag, config.Scheduler.Interval)
+	checkJitter := intOption(cmd, checkJitterFlag, config.Scheduler.Jitter)
+
+	metrics := boolOption(cmd, metricsFlag, config.Metrics)
+	admin := boolOption(cmd, adminFlag, config.Admin)
+
+	reservedPaths := map[string]string{}
+	if metrics {
+		reservedPaths[options.MetricsPath] = metricsFlag.Name
+	}
+	if admin {
+		reservedPaths[options.AdminMaintenancePath] = adminFlag.Name
+	}
+
+	endpoints := config.BuildEndpoints(runAll)
+	for _, endpoint := range endpoints {
+		if flagName, ok := reservedPaths[endpoint.Path]; ok {
+			return nil, ReservedEndpointPath{endpoint.Path, flagName}
+		}
+		for _, name := range endpoint.Checks {
+			if !seen[name] {
+				return nil, UnknownEndpointCheck{endpoint.Path, name}
+			}
+		}
+	}
+	detailedStatus := boolOption(cmd, detailedStatusFlag, config.DetailedStatus)
+
+	auth := config.BuildAuth()
+	auth.BearerTokens = append(auth.BearerTokens, cmd.StringSlice(authTokenFlag.Name)...)
+	for _, cidr := range cmd.StringSlice(authAllowCidrFlag.Name) {
+		prefixes, err := options.ParseCIDRs([]string{cidr})
+		if err != nil {
+			return nil, InvalidCidr{authAllowCidrFlag.Name, cidr}
+		}
+		auth.AllowedCIDRs = append(auth.AllowedCIDRs, prefixes...)
+	}
+	if auth.Enabled() && !detailedStatus && !metrics && !admin {
+		logger.Warnf("Authentication is configured, but has no effect without --%s, --%s or --%s", detailedStatusFlag.Name, metricsFlag.Name, adminFlag.Name)
+	}
+	if admin && !auth.Enabled() {
+		return nil, AdminRequiresAuth{adminFlag.Name, authTokenFlag.Name, authAllowCidrFlag.Name}
+	}
 
-	scriptTimeout := int(cmd.Int("script-timeout"))
-	httpReadTimeout := int(cmd.Int("http-read-timeout"))
-	httpWriteTimeout := int(cmd.Int("http-write-timeout"))
-	httpIdleTimeout := int(cmd.Int("http-idle-timeout"))
-	tcpDialTimeout := int(cmd.Int("tcp-dial-timeout"))
-	httpDialTimeout := int(cmd.Int("http-dial-timeout"))
+	maintenance := options.MaintenanceOptions{
+		File:       stringOption(cmd, maintenanceFileFlag, config.Maintenance.File),
+		StatusCode: intOption(cmd, maintenanceStatusCodeFlag, config.Maintenance.Status),
+		Message:    stringOption(cmd, maintenanceMessageFlag, config.Maintenance.Message),
+	}
+	if maintenance.StatusCode < 100 || maintenance.StatusCode > 599 {
+		return nil, InvalidStatusCode{maintenanceStatusCodeFlag.Name, maintenance.StatusCode}
+	}
+
+	degradedStatus := intOption(cmd, degradedStatusCodeFlag, config.DegradedStatus)
+	if degradedStatus < 100 || degradedStatus > 599 {
+		return nil, InvalidStatusCode{degradedStatusCodeFlag.Name, degradedStatus}
+	}
+	allowInsecureTls := boolOption(cmd, allowInsecureTlsFlag, config.AllowInsecureTLS)
 
-	listener := cmd.String("listener")
+	scriptTimeout := intOption(cmd, scriptTimeoutFlag, config.Timeouts.Script)
+	httpReadTimeout := intOption(cmd, httpReadTimeoutFlag, config.Listener.ReadTimeout)
+	httpWriteTimeout := intOption(cmd, httpWriteTimeoutFlag, config.Listener.WriteTimeout)
+	httpIdleTimeout := intOption(cmd, httpIdleTimeoutFlag, config.Listener.IdleTimeout)
+	tcpDialTimeout := intOption(cmd, tcpDialTimeoutFlag, config.Timeouts.TcpDial)
+	httpDialTimeout := intOption(cmd, httpDialTimeoutFlag, config.Timeouts.HttpDial)
+
+	listener := stringOption(cmd, listenerFlag, config.Listener.Address)
 	if listener == "" {
 		return nil, MissingParam(listenerFlag.Name)
 	}
 
+	drainPeriod := intOption(cmd, drainPeriodFlag, config.Listener.DrainPeriod)
+	if drainPeriod < 0 {
+		return nil, InvalidDrainPeriod(drainPeriod)
+	}
+
+	tlsCert := stringOption(cmd, tlsCertFlag, config.Listener.TLSCert)
+	tlsKey := stringOption(cmd, tlsKeyFlag, config.Listener.TLSKey)
+	tlsClientCa := stringOption(cmd, tlsClientCaFlag, config.Listener.TLSClientCA)
+	if tlsCert != "" && tlsKey == "" {
+		return nil, MissingParam(tlsKeyFlag.Name)
+	}
+	if tlsCert == "" && (tlsKey != "" || tlsClientCa != "") {
+		return nil, MissingParam(tlsCertFlag.Name)
+	}
+
 	return &options.Options{
 		Ports:            ports,
 		Scripts:          scripts,
 		HttpChecks:       httpChecks,
+		DnsChecks:        dnsChecks,
+		TlsChecks:        tlsChecks,
+		UdpChecks:        udpChecks,
 		ScriptTimeout:    scriptTimeout,
 		HttpReadTimeout:  httpReadTimeout,
 		HttpWriteTimeout: httpWriteTimeout,
@@ -198,13 +440,61 @@ func parseOptions(cmd *cli.Command) (*options.Options, error) {
 		TcpDialTimeout:   tcpDialTimeout,
 		HttpDialTimeout:  httpDialTimeout,
 		Singleflight:     singleflight,
+		Background:       background,
+		CheckInterval:    checkInterval,
+		CheckJitter:      checkJitter,
+		RunAll:           runAll,
+		DegradedStatus:   degradedStatus,
 		DetailedStatus:   detailedStatus,
+		Metrics:          metrics,
 		AllowInsecureTLS: allowInsecureTls,
 		Listener:         listener,
+		DrainPeriod:      drainPeriod,
+		Maintenance:      maintenance,
+		Admin:            admin,
+		TLSCert:          tlsCert,
+		TLSKey:           tlsKey,
+		TLSClientCA:      tlsClientCa,
+		Endpoints:        endpoints,
+		Auth:             auth,
 		Logger:           logger.Logger,
 	}, nil
 }
 
+// stringOption returns the value of the given flag if it was explicitly set on the command line, otherwise the value
+// from the config file, and finally the flag's default if the config file does not set it either.
+func stringOption(cmd *cli.Command, flag *cli.StringFlag, configValue string) string {
+	if cmd.IsSet(flag.Name) || configValue == "" {
+		return cmd.String(flag.Name)
+	}
+	return configValue
+}
+
+// intOption is the int equivalent of stringOption. A zero value in the config file is treated as unset.
+func intOption(cmd *cli.Command, flag *cli.IntFlag, configValue int) int {
+	if cmd.IsSet(flag.Name) || configValue == 0 {
+		return int(cmd.Int(flag.Name))
+	}
+	return configValue
+}
+
+// boolOption is the bool equivalent of stringOption.
+func boolOption(cmd *cli.Command, flag *cli.BoolFlag, configValue bool) bool {
+	if cmd.IsSet(flag.Name) {
+		return cmd.Bool(flag.Name)
+	}
+	return configValue
+}
+
+// mergeChecks appends the checks passed as flags to the ones declared in the config file. The flag slice is returned
+// untouched when the config file declares none, so that callers see exactly what they would without a config file.
+func mergeChecks[T any](fromConfig []T, fromFlags []T) []T {
+	if len(fromConfig) == 0 {
+		return fromFlags
+	}
+	return append(fromConfig, fromFlags...)
+}
+
 // Some error types are simple enough that we'd rather just show the error message directly instead of vomiting out a
 // whole stack trace in log output. Therefore, allow a debug mode that always shows full stack traces. Otherwise, show
 // simple messages.
@@ -222,12 +512,70 @@ func (invalidLogLevel InvalidLogLevel) Error() string {
 	return fmt.Sprintf("The log-level value \"%s\" is invalid", string(invalidLogLevel))
 }
 
+type InvalidStatusCode struct {
+	paramName string
+	code      int
+}
+
+func (invalid InvalidStatusCode) Error() string {
+	return fmt.Sprintf("The --%s value %d is not a valid HTTP status code", invalid.paramName, invalid.code)
+}
+
+type InvalidCidr struct {
+	paramName string
+	cidr      string
+}
+
+func (invalid InvalidCidr) Error() string {
+	return fmt.Sprintf("The --%s value \"%s\" is not a valid CIDR range or IP address", invalid.paramName, invalid.cidr)
+}
+
+type InvalidDrainPeriod int
+
+func (drainPeriod InvalidDrainPeriod) Error() string {
+	return fmt.Sprintf("The --drain-period value %d must not be negative", int(drainPeriod))
+}
+
 type MissingParam string
 
 func (paramName MissingParam) Error() string {
 	return fmt.Sprintf("Missing required parameter --%s", string(paramName))
 }
 
+type DuplicateCheckName string
+
+func (checkName DuplicateCheckName) Error() string {
+	return fmt.Sprintf("More than one check is named \"%s\". Check names (or targets, for checks passed as flags) must be unique", string(checkName))
+}
+
+type UnknownEndpointCheck struct {
+	path      string
+	checkName string
+}
+
+func (unknown UnknownEndpointCheck) Error() string {
+	return fmt.Sprintf("Endpoint %s refers to unknown check \"%s\"", unknown.path, unknown.checkName)
+}
+
+type ReservedEndpointPath struct {
+	path      string
+	paramName string
+}
+
+func (reserved ReservedEndpointPath) Error() string {
+	return fmt.Sprintf("Endpoint path %s is reserved when --%s is enabled", reserved.path, reserved.paramName)
+}
+
+type AdminRequiresAuth struct {
+	paramName     string
+	authParamName string
+	cidrParamName string
+}
+
+func (adminRequiresAuth AdminRequiresAuth) Error() string {
+	return fmt.Sprintf("--%s requires authentication to be configured with --%s, --%s or the auth section of the config file", adminRequiresAuth.paramName, adminRequiresAuth.authParamName, adminRequiresAuth.cidrParamName)
+}
+
 type OneOfParamsRequired struct {
 	param1 string
 	param2 string
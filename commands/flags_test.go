@@ -2,6 +2,7 @@ package commands
 
 import (
 	"context"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -40,6 +41,67 @@ func TestParseChecksFromConfig(t *testing.T) {
 	dummyScript1 := createDummyScript(t, tmpDir, "check1", "echo ok")
 	dummyScript2 := createDummyScript(t, tmpDir, "check2", "echo ok")
 
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	err := os.WriteFile(configFile, []byte(`
+singleflight: true
+run_all: true
+scheduler:
+  enabled: true
+  jitter: 3
+endpoints:
+  - path: /
+    run_all: false
+  - path: /debug
+  - role: liveness
+    checks: [app-port]
+    failure_status: 503
+listener:
+  address: 127.0.0.1:6000
+  read_timeout: 7
+timeouts:
+  script: 9
+checks:
+  - name: app-port
+    type: tcp
+    address: "8080"
+  - name: db
+    type: script
+    command: "`+dummyScript+`"
+  - name: api
+    type: http
+    url: http://localhost:8080/health
+    verify_payload: READY
+`), 0644)
+	assert.NoError(t, err)
+
+	unknownCheckConfigFile := filepath.Join(tmpDir, "unknown-check.yaml")
+	err = os.WriteFile(unknownCheckConfigFile, []byte(`
+checks:
+  - {name: app-port, type: tcp, address: "8080"}
+endpoints:
+  - {role: readiness, checks: [app-port, db]}
+`), 0644)
+	assert.NoError(t, err)
+
+	metricsConfigFile := filepath.Join(tmpDir, "metrics.yaml")
+	err = os.WriteFile(metricsConfigFile, []byte(`
+metrics: true
+checks:
+  - {name: app-port, type: tcp, address: "8080"}
+endpoints:
+  - {path: /metrics}
+`), 0644)
+	assert.NoError(t, err)
+
+	fileOnlyConfigFile := filepath.Join(tmpDir, "file-only.yaml")
+	err = os.WriteFile(fileOnlyConfigFile, []byte(`
+checks:
+  - {name: internal-dns, type: dns, host: app.internal, record_type: a}
+  - {name: public-cert, type: tls, address: "example.com:443", expiry_warning_days: 30}
+  - {name: statsd, type: udp, address: "8125", send: ping}
+`), 0644)
+	assert.NoError(t, err)
+
 	testCases := []struct {
 		name            string
 		args            []string
@@ -127,6 +189,219 @@ func TestParseChecksFromConfig(t *testing.T) {
 			}(),
 			"",
 		},
+		{
+			"run-all flag",
+			[]string{"--port", "8080", "--run-all"},
+			func() *options.Options {
+				opts := createOptionsForTest(t, DEFAULT_SCRIPT_TIMEOUT_SEC, []string{}, nil, defaultListener(), []string{"8080"})
+				opts.RunAll = true
+				return opts
+			}(),
+			"",
+		},
+		{
+			"background mode",
+			[]string{"--port", "8080", "--background", "--check-interval", "30"},
+			func() *options.Options {
+				opts := createOptionsForTest(t, DEFAULT_SCRIPT_TIMEOUT_SEC, []string{}, nil, defaultListener(), []string{"8080"})
+				opts.Background = true
+				opts.CheckInterval = 30
+				return opts
+			}(),
+			"",
+		},
+		{
+			"metrics",
+			[]string{"--port", "8080", "--metrics"},
+			func() *options.Options {
+				opts := createOptionsForTest(t, DEFAULT_SCRIPT_TIMEOUT_SEC, []string{}, nil, defaultListener(), []string{"8080"})
+				opts.Metrics = true
+				return opts
+			}(),
+			"",
+		},
+		{
+			"checks only available in the config file",
+			[]string{"--config", fileOnlyConfigFile},
+			func() *options.Options {
+				opts := createOptionsForTest(t, DEFAULT_SCRIPT_TIMEOUT_SEC, []string{}, nil, defaultListener(), nil)
+				opts.DnsChecks = []options.DnsCheck{{CheckOptions: options.CheckOptions{CheckName: "internal-dns"}, Host: "app.internal", RecordType: "A"}}
+				opts.TlsChecks = []options.TlsCheck{{CheckOptions: options.CheckOptions{CheckName: "public-cert"}, Address: "example.com:443", ExpiryWarningDays: 30}}
+				opts.UdpChecks = []options.UdpCheck{{CheckOptions: options.CheckOptions{CheckName: "statsd"}, Probe: options.Probe{Send: []byte("ping")}, Address: "8125"}}
+				return opts
+			}(),
+			"",
+		},
+		{
+			"endpoint on the metrics path",
+			[]string{"--config", metricsConfigFile},
+			nil,
+			"Endpoint path /metrics is reserved when --metrics is enabled",
+		},
+		{
+			"tls listener",
+			[]string{"--port", "8080", "--tls-cert", "/etc/tls.crt", "--tls-key", "/etc/tls.key", "--tls-client-ca", "/etc/ca.crt"},
+			func() *options.Options {
+				opts := createOptionsForTest(t, DEFAULT_SCRIPT_TIMEOUT_SEC, []string{}, nil, defaultListener(), []string{"8080"})
+				opts.TLSCert = "/etc/tls.crt"
+				opts.TLSKey = "/etc/tls.key"
+				opts.TLSClientCA = "/etc/ca.crt"
+				return opts
+			}(),
+			"",
+		},
+		{
+			"tls cert without key",
+			[]string{"--port", "8080", "--tls-cert", "/etc/tls.crt"},
+			nil,
+			"Missing required parameter --tls-key",
+		},
+		{
+			"tls client ca without cert",
+			[]string{"--port", "8080", "--tls-client-ca", "/etc/ca.crt"},
+			nil,
+			"Missing required parameter --tls-cert",
+		},
+		{
+			"auth",
+			[]string{"--port", "8080", "--detailed-status", "--auth-token", "token", "--auth-allow-cidr", "10.0.0.0/8", "--auth-allow-cidr", "192.0.2.7"},
+			func() *options.Options {
+				opts := createOptionsForTest(t, DEFAULT_SCRIPT_TIMEOUT_SEC, []string{}, nil, defaultListener(), []string{"8080"})
+				opts.DetailedStatus = true
+				opts.Auth = options.AuthOptions{
+					BearerTokens: []string{"token"},
+					AllowedCIDRs: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.0.2.7/32")},
+				}
+				return opts
+			}(),
+			"",
+		},
+		{
+			"invalid auth cidr",
+			[]string{"--port", "8080", "--auth-allow-cidr", "10.0.0.0/33"},
+			nil,
+			"The --auth-allow-cidr value \"10.0.0.0/33\" is not a valid CIDR range or IP address",
+		},
+		{
+			"drain period",
+			[]string{"--port", "8080", "--drain-period", "30"},
+			func() *options.Options {
+				opts := createOptionsForTest(t, DEFAULT_SCRIPT_TIMEOUT_SEC, []string{}, nil, defaultListener(), []string{"8080"})
+				opts.DrainPeriod = 30
+				return opts
+			}(),
+			"",
+		},
+		{
+			"negative drain period",
+			[]string{"--port", "8080", "--drain-period", "-1"},
+			nil,
+			"The --drain-period value -1 must not be negative",
+		},
+		{
+			"maintenance",
+			[]string{"--port", "8080", "--maintenance-file", "/etc/maintenance", "--maintenance-status-code", "418", "--maintenance-message", "upgrading", "--admin", "--auth-token", "token"},
+			func() *options.Options {
+				opts := createOptionsForTest(t, DEFAULT_SCRIPT_TIMEOUT_SEC, []string{}, nil, defaultListener(), []string{"8080"})
+				opts.Maintenance = options.MaintenanceOptions{File: "/etc/maintenance", StatusCode: 418, Message: "upgrading"}
+				opts.Admin = true
+				opts.Auth = options.AuthOptions{BearerTokens: []string{"token"}}
+				return opts
+			}(),
+			"",
+		},
+		{
+			"invalid maintenance status code",
+			[]string{"--port", "8080", "--maintenance-status-code", "1000"},
+			nil,
+			"The --maintenance-status-code value 1000 is not a valid HTTP status code",
+		},
+		{
+			"admin without auth",
+			[]string{"--port", "8080", "--admin"},
+			nil,
+			"--admin requires authentication to be configured with --auth-token, --auth-allow-cidr or the auth section of the config file",
+		},
+		{
+			"degraded status code",
+			[]string{"--port", "8080", "--degraded-status-code", "207"},
+			func() *options.Options {
+				opts := createOptionsForTest(t, DEFAULT_SCRIPT_TIMEOUT_SEC, []string{}, nil, defaultListener(), []string{"8080"})
+				opts.DegradedStatus = 207
+				return opts
+			}(),
+			"",
+		},
+		{
+			"invalid degraded status code",
+			[]string{"--port", "8080", "--degraded-status-code", "42"},
+			nil,
+			"The --degraded-status-code value 42 is not a valid HTTP status code",
+		},
+		{
+			"config file",
+			[]string{"--config", configFile},
+			func() *options.Options {
+				opts := createOptionsForTest(t, 9, []string{dummyScript}, []options.HttpCheck{
+					{Url: "http://localhost:8080/health", VerifyPayload: "READY"},
+				}, test.ListenerString("127.0.0.1", 6000), []string{"8080"})
+				opts.HttpReadTimeout = 7
+				opts.RunAll = true
+				opts.Background = true
+				opts.CheckJitter = 3
+				opts.Endpoints = []options.Endpoint{
+					{Path: "/", RunAll: false},
+					{Path: "/debug", RunAll: true},
+					{Path: "/livez", Role: options.EndpointRoleLiveness, Checks: []string{"app-port"}, FailureStatusCode: 503, RunAll: true},
+				}
+				opts.Ports[0].CheckName = "app-port"
+				opts.Scripts[0].CheckName = "db"
+				opts.HttpChecks[0].CheckName = "api"
+				return opts
+			}(),
+			"",
+		},
+		{
+			"flags override config file",
+			[]string{"--config", configFile, "--script-timeout", "3", "--listener", test.ListenerString("127.0.0.1", 7000), "--port", "9090"},
+			func() *options.Options {
+				opts := createOptionsForTest(t, 3, []string{dummyScript}, []options.HttpCheck{
+					{Url: "http://localhost:8080/health", VerifyPayload: "READY"},
+				}, test.ListenerString("127.0.0.1", 7000), []string{"8080", "9090"})
+				opts.HttpReadTimeout = 7
+				opts.RunAll = true
+				opts.Background = true
+				opts.CheckJitter = 3
+				opts.Endpoints = []options.Endpoint{
+					{Path: "/", RunAll: false},
+					{Path: "/debug", RunAll: true},
+					{Path: "/livez", Role: options.EndpointRoleLiveness, Checks: []string{"app-port"}, FailureStatusCode: 503, RunAll: true},
+				}
+				opts.Ports[0].CheckName = "app-port"
+				opts.Scripts[0].CheckName = "db"
+				opts.HttpChecks[0].CheckName = "api"
+				return opts
+			}(),
+			"",
+		},
+		{
+			"duplicate flag checks",
+			[]string{"--port", "8080", "--port", "8080"},
+			nil,
+			"More than one check is named \"8080\"",
+		},
+		{
+			"endpoint with unknown check",
+			[]string{"--config", unknownCheckConfigFile},
+			nil,
+			"Endpoint /readyz refers to unknown check \"db\"",
+		},
+		{
+			"missing config file",
+			[]string{"--config", filepath.Join(tmpDir, "does-not-exist.yaml")},
+			nil,
+			"failed to read config file",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -159,7 +434,7 @@ func TestParseChecksFromConfig(t *testing.T) {
 			} else {
 				assert.Nil(t, actualErr, "Unexpected error: %v", actualErr)
 				if testCase.expectedOptions.Ports != nil && len(testCase.expectedOptions.Ports) == 0 {
-					testCase.expectedOptions.Ports = make([]string, 0)
+					testCase.expectedOptions.Ports = make([]options.PortCheck, 0)
 				}
 				assertOptionsEqual(t, *testCase.expectedOptions, *actualOptions, "For args %v", testCase.args)
 			}
@@ -180,9 +455,27 @@ func assertOptionsEqual(t *testing.T, expected options.Options, actual options.O
 	assert.Equal(t, expected.TcpDialTimeout, actual.TcpDialTimeout, msgAndArgs...)
 	assert.Equal(t, expected.HttpDialTimeout, actual.HttpDialTimeout, msgAndArgs...)
 	assert.Equal(t, expected.AllowInsecureTLS, actual.AllowInsecureTLS, msgAndArgs...)
+	assert.Equal(t, expected.RunAll, actual.RunAll, msgAndArgs...)
+	assert.Equal(t, expected.DegradedStatus, actual.DegradedStatus, msgAndArgs...)
+	assert.Equal(t, expected.Metrics, actual.Metrics, msgAndArgs...)
+	assert.Equal(t, expected.DetailedStatus, actual.DetailedStatus, msgAndArgs...)
+	assert.Equal(t, expected.Auth, actual.Auth, msgAndArgs...)
+	assert.Equal(t, expected.Background, actual.Background, msgAndArgs...)
+	assert.Equal(t, expected.CheckInterval, actual.CheckInterval, msgAndArgs...)
+	assert.Equal(t, expected.CheckJitter, actual.CheckJitter, msgAndArgs...)
+	assert.Equal(t, expected.Endpoints, actual.Endpoints, msgAndArgs...)
 	assert.Equal(t, expected.Scripts, actual.Scripts, msgAndArgs...)
 	assert.Equal(t, expected.HttpChecks, actual.HttpChecks, msgAndArgs...)
+	assert.Equal(t, expected.DnsChecks, actual.DnsChecks, msgAndArgs...)
+	assert.Equal(t, expected.TlsChecks, actual.TlsChecks, msgAndArgs...)
+	assert.Equal(t, expected.UdpChecks, actual.UdpChecks, msgAndArgs...)
 	assert.Equal(t, expected.Listener, actual.Listener, msgAndArgs...)
+	assert.Equal(t, expected.DrainPeriod, actual.DrainPeriod, msgAndArgs...)
+	assert.Equal(t, expected.TLSCert, actual.TLSCert, msgAndArgs...)
+	assert.Equal(t, expected.TLSKey, actual.TLSKey, msgAndArgs...)
+	assert.Equal(t, expected.TLSClientCA, actual.TLSClientCA, msgAndArgs...)
+	assert.Equal(t, expected.Maintenance, actual.Maintenance, msgAndArgs...)
+	assert.Equal(t, expected.Admin, actual.Admin, msgAndArgs...)
 	assert.Equal(t, expected.Ports, actual.Ports, msgAndArgs...)
 }
 
@@ -194,6 +487,10 @@ func createOptionsForTest(t *testing.T, scriptTimeout int, scripts []string, htt
 	opts.HttpIdleTimeout = 15
 	opts.TcpDialTimeout = 5
 	opts.HttpDialTimeout = 5
+	opts.DegradedStatus = options.DefaultDegradedStatusCode
+	opts.CheckInterval = DEFAULT_CHECK_INTERVAL_SEC
+	opts.CheckJitter = DEFAULT_CHECK_JITTER_SEC
+	opts.Maintenance = options.MaintenanceOptions{StatusCode: options.DefaultMaintenanceStatusCode, Message: options.DefaultMaintenanceMessage}
 
 	parsedScripts, err := options.ParseScripts(scripts)
 	assert.NoError(t, err)
@@ -201,6 +498,22 @@ func createOptionsForTest(t *testing.T, scriptTimeout int, scripts []string, htt
 	opts.HttpChecks = httpChecks
 
 	opts.Listener = listener
-	opts.Ports = ports
+	opts.Ports = options.ParsePorts(ports)
 	return opts
 }
+
+func TestAuthTokenFromEnvironment(t *testing.T) {
+	t.Setenv(ENV_VAR_NAME_AUTH_TOKEN, "from-env")
+
+	var actualOptions *options.Options
+	app := CreateCli("0.0.0")
+	app.Action = func(ctx context.Context, cmd *cli.Command) error {
+		var err error
+		actualOptions, err = parseOptions(cmd)
+		return err
+	}
+
+	err := app.Run(context.Background(), []string{"health-checker", "--port", "8080", "--detailed-status"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"from-env"}, actualOptions.Auth.BearerTokens)
+}
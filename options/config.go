@@ -0,0 +1,750 @@
+package options
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// The check types that may be declared in a configuration file.
+const (
+	CheckTypeTcp    = "tcp"
+	CheckTypeScript = "script"
+	CheckTypeHttp   = "http"
+	CheckTypeDns    = "dns"
+	CheckTypeTls    = "tls"
+	CheckTypeUdp    = "udp"
+)
+
+// httpTokenPattern matches the HTTP methods and header names that may be configured, as defined by RFC 9110.
+var httpTokenPattern = regexp.MustCompile("^[!#$%&'*+\\-.^_`|~0-9A-Za-z]+$")
+
+// Config is the on-disk representation of a health-checker configuration file (YAML or JSON). It covers the same
+// settings as the command-line flags, but lets every check be declared as a named entry with its own settings
+// instead of relying on the positional pairing of repeated flags.
+type Config struct {
+	LogLevel         string            `yaml:"log_level" json:"log_level"`
+	Singleflight     bool              `yaml:"singleflight" json:"singleflight"`
+	RunAll           bool              `yaml:"run_all" json:"run_all"`
+	DegradedStatus   int               `yaml:"degraded_status" json:"degraded_status"`
+	DetailedStatus   bool              `yaml:"detailed_status" json:"detailed_status"`
+	Metrics          bool              `yaml:"metrics" json:"metrics"`
+	AllowInsecureTLS bool              `yaml:"allow_insecure_tls" json:"allow_insecure_tls"`
+	Listener         ListenerConfig    `yaml:"listener" json:"listener"`
+	Timeouts         TimeoutConfig     `yaml:"timeouts" json:"timeouts"`
+	Scheduler        SchedulerConfig   `yaml:"scheduler" json:"scheduler"`
+	Checks           []CheckConfig     `yaml:"checks" json:"checks"`
+	Endpoints        []EndpointConfig  `yaml:"endpoints" json:"endpoints"`
+	Auth             AuthConfig        `yaml:"auth" json:"auth"`
+	Maintenance      MaintenanceConfig `yaml:"maintenance" json:"maintenance"`
+	Admin            bool              `yaml:"admin" json:"admin"`
+}
+
+// ListenerConfig holds the settings of the inbound HTTP listener. Timeouts and the drain period are expressed in seconds. The listener serves
+// HTTPS when TLSCert and TLSKey are set, and additionally requires client certificates signed by TLSClientCA if set.
+type ListenerConfig struct {
+	Address      string `yaml:"address" json:"address"`
+	ReadTimeout  int    `yaml:"read_timeout" json:"read_timeout"`
+	WriteTimeout int    `yaml:"write_timeout" json:"write_timeout"`
+	IdleTimeout  int    `yaml:"idle_timeout" json:"idle_timeout"`
+	DrainPeriod  int    `yaml:"drain_period" json:"drain_period"`
+	TLSCert      string `yaml:"tls_cert" json:"tls_cert"`
+	TLSKey       string `yaml:"tls_key" json:"tls_key"`
+	TLSClientCA  string `yaml:"tls_client_ca" json:"tls_client_ca"`
+}
+
+// TimeoutConfig holds the default outbound probe timeouts, in seconds.
+type TimeoutConfig struct {
+	Script   int `yaml:"script" json:"script"`
+	TcpDial  int `yaml:"tcp_dial" json:"tcp_dial"`
+	HttpDial int `yaml:"http_dial" json:"http_dial"`
+}
+
+// SchedulerConfig holds the settings of background mode. Interval and Jitter are in seconds.
+type SchedulerConfig struct {
+	Enabled  bool `yaml:"enabled" json:"enabled"`
+	Interval int  `yaml:"interval" json:"interval"`
+	Jitter   int  `yaml:"jitter" json:"jitter"`
+}
+
+// MaintenanceConfig configures maintenance mode. See MaintenanceOptions.
+type MaintenanceConfig struct {
+	File    string `yaml:"file" json:"file"`
+	Status  int    `yaml:"status" json:"status"`
+	Message string `yaml:"message" json:"message"`
+}
+
+// AuthConfig lists who may see the detailed status output. Basic auth passwords are given as their bcrypt hash, as
+// generated by htpasswd, so that the file does not contain them in clear text.
+type AuthConfig struct {
+	BearerTokens []string          `yaml:"bearer_tokens" json:"bearer_tokens"`
+	Basic        []BasicAuthConfig `yaml:"basic" json:"basic"`
+	AllowedCidrs []string          `yaml:"allowed_cidrs" json:"allowed_cidrs"`
+}
+
+// BasicAuthConfig declares a user allowed to authenticate with HTTP basic auth.
+type BasicAuthConfig struct {
+	Username       string `yaml:"username" json:"username"`
+	PasswordBcrypt string `yaml:"password_bcrypt" json:"password_bcrypt"`
+}
+
+// CheckConfig declares a single named check. Which of the target fields is required depends on Type. Timeout and
+// Interval are in seconds and override the global timeout for the check's type and the scheduler interval when set.
+type CheckConfig struct {
+	Name             string `yaml:"name" json:"name"`
+	Type             string `yaml:"type" json:"type"`
+	Timeout          int    `yaml:"timeout" json:"timeout"`
+	Interval         int    `yaml:"interval" json:"interval"`
+	FailureThreshold int    `yaml:"failure_threshold" json:"failure_threshold"`
+	SuccessThreshold int    `yaml:"success_threshold" json:"success_threshold"`
+	Severity         string `yaml:"severity" json:"severity"`
+
+	// tcp, tls, udp
+	Address string `yaml:"address" json:"address"`
+
+	// tcp, udp
+	Send      string `yaml:"send" json:"send"`
+	SendHex   string `yaml:"send_hex" json:"send_hex"`
+	Expect    string `yaml:"expect" json:"expect"`
+	ExpectHex string `yaml:"expect_hex" json:"expect_hex"`
+
+	// tcp
+	ReadTimeoutMs int `yaml:"read_timeout_ms" json:"read_timeout_ms"`
+	MaxBytes      int `yaml:"max_bytes" json:"max_bytes"`
+
+	// script
+	Command string `yaml:"command" json:"command"`
+	Mode    string `yaml:"mode" json:"mode"`
+
+	// http
+	Url           string         `yaml:"url" json:"url"`
+	VerifyPayload string         `yaml:"verify_payload" json:"verify_payload"`
+	Method        string         `yaml:"method" json:"method"`
+	Headers       []HeaderConfig `yaml:"headers" json:"headers"`
+	Body          string         `yaml:"body" json:"body"`
+	UserAgent     string         `yaml:"user_agent" json:"user_agent"`
+	// ExpectedStatus is a list of status codes and ranges, such as "200,204,301-302,401"
+	ExpectedStatus     string                  `yaml:"expected_status" json:"expected_status"`
+	FollowRedirects    *bool                   `yaml:"follow_redirects" json:"follow_redirects"`
+	MaxRedirects       int                     `yaml:"max_redirects" json:"max_redirects"`
+	FinalUrlRegex      string                  `yaml:"final_url_regex" json:"final_url_regex"`
+	JsonAssertions     []JsonAssertionConfig   `yaml:"json_assertions" json:"json_assertions"`
+	HeaderAssertions   []HeaderAssertionConfig `yaml:"header_assertions" json:"header_assertions"`
+	LatencyWarningMs   int                     `yaml:"latency_warning_ms" json:"latency_warning_ms"`
+	LatencyCriticalMs  int                     `yaml:"latency_critical_ms" json:"latency_critical_ms"`
+	ClientCertFile     string                  `yaml:"client_cert_file" json:"client_cert_file"`
+	ClientKeyFile      string                  `yaml:"client_key_file" json:"client_key_file"`
+	MinTlsVersion      string                  `yaml:"min_tls_version" json:"min_tls_version"`
+	PinnedFingerprints []string                `yaml:"pinned_fingerprints" json:"pinned_fingerprints"`
+
+	// dns, http
+	Host          string   `yaml:"host" json:"host"`
+	Resolver      string   `yaml:"resolver" json:"resolver"`
+	RecordType    string   `yaml:"record_type" json:"record_type"`
+	Answers       []string `yaml:"answers" json:"answers"`
+	AnswerRegex   string   `yaml:"answer_regex" json:"answer_regex"`
+	MaxDurationMs int      `yaml:"max_duration_ms" json:"max_duration_ms"`
+
+	// tls
+	CertFile string `yaml:"cert_file" json:"cert_file"`
+
+	// tls, http
+	ServerName string `yaml:"server_name" json:"server_name"`
+	CAFile     string `yaml:"ca_file" json:"ca_file"`
+
+	// tls
+	ExpiryWarningDays  int `yaml:"expiry_warning_days" json:"expiry_warning_days"`
+	ExpiryCriticalDays int `yaml:"expiry_critical_days" json:"expiry_critical_days"`
+}
+
+// JsonAssertionConfig declares an assertion on the JSON response of an HTTP check. See JsonAssertion.
+type JsonAssertionConfig struct {
+	Path   string   `yaml:"path" json:"path"`
+	Equals *string  `yaml:"equals" json:"equals"`
+	Regex  string   `yaml:"regex" json:"regex"`
+	Min    *float64 `yaml:"min" json:"min"`
+	Max    *float64 `yaml:"max" json:"max"`
+	Exists *bool    `yaml:"exists" json:"exists"`
+}
+
+// HeaderAssertionConfig declares an assertion on a header of the response of an HTTP check. See HeaderAssertion.
+type HeaderAssertionConfig struct {
+	Name   string  `yaml:"name" json:"name"`
+	Equals *string `yaml:"equals" json:"equals"`
+	Regex  string  `yaml:"regex" json:"regex"`
+	Exists *bool   `yaml:"exists" json:"exists"`
+}
+
+// HeaderConfig declares a header sent by an HTTP check. See HttpHeader.
+type HeaderConfig struct {
+	Name      string `yaml:"name" json:"name"`
+	Value     string `yaml:"value" json:"value"`
+	ValueFile string `yaml:"value_file" json:"value_file"`
+	ValueEnv  string `yaml:"value_env" json:"value_env"`
+}
+
+// checkOptions returns the settings shared by all check types.
+func (check CheckConfig) checkOptions() CheckOptions {
+	return CheckOptions{
+		CheckName:        check.Name,
+		Timeout:          check.Timeout,
+		Interval:         check.Interval,
+		FailureThreshold: check.FailureThreshold,
+		SuccessThreshold: check.SuccessThreshold,
+		Severity:         check.Severity,
+	}
+}
+
+// EndpointConfig declares an HTTP path on the listener and the checks it runs. Path defaults to the conventional path
+// of the Role, if one is given. RunAll overrides the global run_all setting when present.
+type EndpointConfig struct {
+	Path           string   `yaml:"path" json:"path"`
+	Role           string   `yaml:"role" json:"role"`
+	Checks         []string `yaml:"checks" json:"checks"`
+	FailureStatus  int      `yaml:"failure_status" json:"failure_status"`
+	DegradedStatus int      `yaml:"degraded_status" json:"degraded_status"`
+	RunAll         *bool    `yaml:"run_all" json:"run_all"`
+}
+
+// EffectivePath returns the configured path, or the default path of the endpoint's role.
+func (endpoint EndpointConfig) EffectivePath() string {
+	if endpoint.Path == "" {
+		return DefaultEndpointPaths[endpoint.Role]
+	}
+	return endpoint.Path
+}
+
+// LoadConfig reads and validates the configuration file at the given path. Files with a .json extension are decoded
+// as JSON, anything else as YAML. Unknown keys, and keys that don't apply to the type of a check, are rejected so that a
+// typo cannot silently disable a check.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	config := &Config{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(config)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(config)
+		// An empty YAML document is a valid (if useless) config file
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+func (config *Config) validate() error {
+	seen := map[string]bool{}
+	for i, check := range config.Checks {
+		if check.Name == "" {
+			return fmt.Errorf("check #%d is missing a name", i+1)
+		}
+		if check.Timeout < 0 {
+			return fmt.Errorf("check %q has a negative timeout", check.Name)
+		}
+		if check.Interval < 0 {
+			return fmt.Errorf("check %q has a negative interval", check.Name)
+		}
+		if check.FailureThreshold < 0 || check.SuccessThreshold < 0 {
+			return fmt.Errorf("check %q has a negative threshold", check.Name)
+		}
+		switch check.Severity {
+		case "", SeverityCritical, SeverityWarning, SeverityInfo:
+		default:
+			return fmt.Errorf("check %q has unknown severity %q, must be one of: %s, %s, %s", check.Name, check.Severity, SeverityCritical, SeverityWarning, SeverityInfo)
+		}
+		if seen[check.Name] {
+			return fmt.Errorf("check name %q is used more than once", check.Name)
+		}
+		seen[check.Name] = true
+
+		switch check.Type {
+		case CheckTypeTcp:
+			if check.Address == "" {
+				return fmt.Errorf("check %q of type %s requires an address", check.Name, check.Type)
+			}
+			if err := check.validateProbe(); err != nil {
+				return err
+			}
+			if check.ReadTimeoutMs < 0 {
+				return fmt.Errorf("check %q has a negative read_timeout_ms", check.Name)
+			}
+			if check.MaxBytes < 0 {
+				return fmt.Errorf("check %q has a negative max_bytes", check.Name)
+			}
+		case CheckTypeScript:
+			if check.Command == "" {
+				return fmt.Errorf("check %q of type %s requires a command", check.Name, check.Type)
+			}
+			switch check.Mode {
+			case "", ScriptModeExitCode, ScriptModeNagios, ScriptModeJson:
+			default:
+				return fmt.Errorf("check %q has unknown mode %q, must be one of: %s, %s, %s", check.Name, check.Mode, ScriptModeExitCode, ScriptModeNagios, ScriptModeJson)
+			}
+		case CheckTypeHttp:
+			if check.Url == "" {
+				return fmt.Errorf("check %q of type %s requires a url", check.Name, check.Type)
+			}
+			if check.Method != "" && !httpTokenPattern.MatchString(check.Method) {
+				return fmt.Errorf("check %q has an invalid method %q", check.Name, check.Method)
+			}
+			for _, header := range check.Headers {
+				if !httpTokenPattern.MatchString(header.Name) {
+					return fmt.Errorf("check %q has a header with an invalid name %q", check.Name, header.Name)
+				}
+				if header.ValueFile != "" && header.ValueEnv != "" {
+					return fmt.Errorf("check %q can't set both value_file and value_env for header %s", check.Name, header.Name)
+				}
+			}
+			if check.ExpectedStatus != "" {
+				if _, err := ParseStatusRanges(check.ExpectedStatus); err != nil {
+					return fmt.Errorf("check %q has an invalid expected_status: %w", check.Name, err)
+				}
+			}
+			if check.MaxRedirects < 0 {
+				return fmt.Errorf("check %q has a negative max_redirects", check.Name)
+			}
+			if _, err := regexp.Compile(check.FinalUrlRegex); err != nil {
+				return fmt.Errorf("check %q has an invalid final_url_regex: %w", check.Name, err)
+			}
+			for _, assertion := range check.JsonAssertions {
+				if err := assertion.validate(); err != nil {
+					return fmt.Errorf("check %q has an invalid JSON assertion: %w", check.Name, err)
+				}
+			}
+			for _, assertion := range check.HeaderAssertions {
+				if err := assertion.validate(); err != nil {
+					return fmt.Errorf("check %q has an invalid header assertion: %w", check.Name, err)
+				}
+			}
+			if check.LatencyWarningMs < 0 || check.LatencyCriticalMs < 0 {
+				return fmt.Errorf("check %q has a negative latency budget", check.Name)
+			}
+			if (check.ClientCertFile == "") != (check.ClientKeyFile == "") {
+				return fmt.Errorf("check %q must set both client_cert_file and client_key_file, or neither", check.Name)
+			}
+			if _, ok := TlsVersions[check.MinTlsVersion]; check.MinTlsVersion != "" && !ok {
+				return fmt.Errorf("check %q has unknown min_tls_version %q, must be one of: 1.0, 1.1, 1.2, 1.3", check.Name, check.MinTlsVersion)
+			}
+			for _, fingerprint := range check.PinnedFingerprints {
+				if decoded, err := decodeHex(fingerprint); err != nil || len(decoded) != sha256.Size {
+					return fmt.Errorf("check %q has an invalid pinned fingerprint %q, must be a hex encoded SHA-256 hash", check.Name, fingerprint)
+				}
+			}
+		case CheckTypeDns:
+			if check.Host == "" {
+				return fmt.Errorf("check %q of type %s requires a host", check.Name, check.Type)
+			}
+			if check.RecordType != "" && !slices.Contains(DnsRecordTypes, strings.ToUpper(check.RecordType)) {
+				return fmt.Errorf("check %q has unknown record_type %q, must be one of: %s", check.Name, check.RecordType, strings.Join(DnsRecordTypes, ", "))
+			}
+			if _, err := regexp.Compile(check.AnswerRegex); err != nil {
+				return fmt.Errorf("check %q has an invalid answer_regex: %w", check.Name, err)
+			}
+			if check.MaxDurationMs < 0 {
+				return fmt.Errorf("check %q has a negative max_duration_ms", check.Name)
+			}
+		case CheckTypeTls:
+			if (check.Address == "") == (check.CertFile == "") {
+				return fmt.Errorf("check %q of type %s requires either an address or a cert_file", check.Name, check.Type)
+			}
+			if check.ExpiryWarningDays < 0 || check.ExpiryCriticalDays < 0 {
+				return fmt.Errorf("check %q has a negative expiry window", check.Name)
+			}
+		case CheckTypeUdp:
+			if check.Address == "" {
+				return fmt.Errorf("check %q of type %s requires an address", check.Name, check.Type)
+			}
+			if err := check.validateProbe(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("check %q has unknown type %q, must be one of: %s, %s, %s, %s, %s, %s", check.Name, check.Type, CheckTypeTcp, CheckTypeScript, CheckTypeHttp, CheckTypeDns, CheckTypeTls, CheckTypeUdp)
+		}
+		if err := check.validateFields(); err != nil {
+			return err
+		}
+	}
+
+	seenPaths := map[string]bool{}
+	for i, endpoint := range config.Endpoints {
+		if _, ok := DefaultEndpointPaths[endpoint.Role]; endpoint.Role != "" && !ok {
+			return fmt.Errorf("endpoint #%d has unknown role %q, must be one of: %s, %s, %s", i+1, endpoint.Role, EndpointRoleLiveness, EndpointRoleReadiness, EndpointRoleStartup)
+		}
+		path := endpoint.EffectivePath()
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("endpoint #%d must have a path starting with /", i+1)
+		}
+		if !literalPath(path) {
+			return fmt.Errorf("endpoint path %q must be a literal path without spaces, braces or characters that need escaping", path)
+		}
+		if seenPaths[path] {
+			return fmt.Errorf("endpoint path %q is used more than once", path)
+		}
+		seenPaths[path] = true
+
+		if !validStatusCode(endpoint.FailureStatus) {
+			return fmt.Errorf("endpoint %q has invalid failure_status %d", path, endpoint.FailureStatus)
+		}
+		if !validStatusCode(endpoint.DegradedStatus) {
+			return fmt.Errorf("endpoint %q has invalid degraded_status %d", path, endpoint.DegradedStatus)
+		}
+	}
+
+	if config.Listener.DrainPeriod < 0 {
+		return fmt.Errorf("negative listener drain_period")
+	}
+	if !validStatusCode(config.DegradedStatus) {
+		return fmt.Errorf("invalid degraded_status %d", config.DegradedStatus)
+	}
+	if !validStatusCode(config.Maintenance.Status) {
+		return fmt.Errorf("invalid maintenance status %d", config.Maintenance.Status)
+	}
+
+	for i, token := range config.Auth.BearerTokens {
+		if token == "" {
+			return fmt.Errorf("auth bearer token #%d is empty", i+1)
+		}
+	}
+	for i, credential := range config.Auth.Basic {
+		if credential.Username == "" {
+			return fmt.Errorf("auth basic credential #%d is missing a username", i+1)
+		}
+		if _, err := bcrypt.Cost([]byte(credential.PasswordBcrypt)); err != nil {
+			return fmt.Errorf("auth basic credential %q must have a password_bcrypt, as generated by htpasswd -nbB: %w", credential.Username, err)
+		}
+	}
+	if _, err := ParseCIDRs(config.Auth.AllowedCidrs); err != nil {
+		return fmt.Errorf("auth allowed_cidrs: %w", err)
+	}
+	return nil
+}
+
+// literalPath reports whether every segment of the given path is used as is by the HTTP mux, rather than parsed as part
+// of a pattern such as "GET /path" or "/items/{id}".
+func literalPath(path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		if url.PathEscape(segment) != segment {
+			return false
+		}
+	}
+	return true
+}
+
+// validStatusCode reports whether the given configured status code is either unset (0) or a valid HTTP status code.
+func validStatusCode(code int) bool {
+	return code == 0 || (code >= 100 && code <= 599)
+}
+
+// BuildEndpoints converts the declared endpoints into Endpoints, resolving settings they don't override from the
+// given global values.
+func (config *Config) BuildEndpoints(runAll bool) []Endpoint {
+	var endpoints []Endpoint
+	for _, endpoint := range config.Endpoints {
+		resolved := Endpoint{
+			Path:               endpoint.EffectivePath(),
+			Role:               endpoint.Role,
+			Checks:             endpoint.Checks,
+			FailureStatusCode:  endpoint.FailureStatus,
+			DegradedStatusCode: endpoint.DegradedStatus,
+			RunAll:             runAll,
+		}
+		if endpoint.RunAll != nil {
+			resolved.RunAll = *endpoint.RunAll
+		}
+		endpoints = append(endpoints, resolved)
+	}
+	return endpoints
+}
+
+// BuildAuth converts the auth section into AuthOptions. The config must have been validated.
+func (config *Config) BuildAuth() AuthOptions {
+	auth := AuthOptions{BearerTokens: config.Auth.BearerTokens}
+	for _, credential := range config.Auth.Basic {
+		auth.BasicCredentials = append(auth.BasicCredentials, BasicCredential{
+			Username:       credential.Username,
+			PasswordBcrypt: credential.PasswordBcrypt,
+		})
+	}
+	auth.AllowedCIDRs, _ = ParseCIDRs(config.Auth.AllowedCidrs)
+	return auth
+}
+
+// BuildChecks converts the declared TCP, script and HTTP checks into the same structures that are produced from the
+// command-line flags, preserving the order in which they were declared.
+func (config *Config) BuildChecks() ([]PortCheck, []Script, []HttpCheck, error) {
+	var ports []PortCheck
+	var scripts []Script
+	var httpChecks []HttpCheck
+
+	for _, check := range config.Checks {
+		checkOptions := check.checkOptions()
+
+		switch check.Type {
+		case CheckTypeTcp:
+			ports = append(ports, PortCheck{
+				CheckOptions:  checkOptions,
+				Probe:         check.probe(),
+				Address:       check.Address,
+				ReadTimeoutMs: check.ReadTimeoutMs,
+				MaxBytes:      check.MaxBytes,
+			})
+		case CheckTypeScript:
+			parsed, err := ParseScripts([]string{check.Command})
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("check %q: %w", check.Name, err)
+			}
+			parsed[0].CheckOptions = checkOptions
+			parsed[0].Mode = check.Mode
+			scripts = append(scripts, parsed[0])
+		case CheckTypeHttp:
+			var expectedStatus StatusRanges
+			if check.ExpectedStatus != "" {
+				// The status codes were validated along with the rest of the config
+				expectedStatus, _ = ParseStatusRanges(check.ExpectedStatus)
+			}
+			var headers []HttpHeader
+			for _, header := range check.Headers {
+				headers = append(headers, HttpHeader(header))
+			}
+			var jsonAssertions []JsonAssertion
+			for _, assertion := range check.JsonAssertions {
+				jsonAssertions = append(jsonAssertions, JsonAssertion(assertion))
+			}
+			var headerAssertions []HeaderAssertion
+			for _, assertion := range check.HeaderAssertions {
+				headerAssertions = append(headerAssertions, HeaderAssertion(assertion))
+			}
+			var pinnedFingerprints [][]byte
+			for _, fingerprint := range check.PinnedFingerprints {
+				decoded, _ := decodeHex(fingerprint)
+				pinnedFingerprints = append(pinnedFingerprints, decoded)
+			}
+			httpChecks = append(httpChecks, HttpCheck{
+				CheckOptions:       checkOptions,
+				Url:                check.Url,
+				VerifyPayload:      check.VerifyPayload,
+				Method:             strings.ToUpper(check.Method),
+				Headers:            headers,
+				ExpectedStatus:     expectedStatus,
+				Body:               check.Body,
+				Host:               check.Host,
+				UserAgent:          check.UserAgent,
+				NoRedirects:        check.FollowRedirects != nil && !*check.FollowRedirects,
+				MaxRedirects:       check.MaxRedirects,
+				FinalUrlRegex:      check.FinalUrlRegex,
+				JsonAssertions:     jsonAssertions,
+				HeaderAssertions:   headerAssertions,
+				LatencyWarningMs:   check.LatencyWarningMs,
+				LatencyCriticalMs:  check.LatencyCriticalMs,
+				ServerName:         check.ServerName,
+				CAFile:             check.CAFile,
+				ClientCertFile:     check.ClientCertFile,
+				ClientKeyFile:      check.ClientKeyFile,
+				MinTlsVersion:      TlsVersions[check.MinTlsVersion],
+				PinnedFingerprints: pinnedFingerprints,
+			})
+		}
+	}
+	return ports, scripts, httpChecks, nil
+}
+
+// BuildDnsChecks converts the declared DNS checks into DnsChecks, preserving the order in which they were declared. The
+// config must have been validated.
+func (config *Config) BuildDnsChecks() []DnsCheck {
+	var dnsChecks []DnsCheck
+	for _, check := range config.Checks {
+		if check.Type != CheckTypeDns {
+			continue
+		}
+		dnsChecks = append(dnsChecks, DnsCheck{
+			CheckOptions:  check.checkOptions(),
+			Host:          check.Host,
+			Resolver:      check.Resolver,
+			RecordType:    strings.ToUpper(check.RecordType),
+			Answers:       check.Answers,
+			AnswerRegex:   check.AnswerRegex,
+			MaxDurationMs: check.MaxDurationMs,
+		})
+	}
+	return dnsChecks
+}
+
+// BuildTlsChecks converts the declared TLS checks into TlsChecks, preserving the order in which they were declared.
+func (config *Config) BuildTlsChecks() []TlsCheck {
+	var tlsChecks []TlsCheck
+	for _, check := range config.Checks {
+		if check.Type != CheckTypeTls {
+			continue
+		}
+		tlsChecks = append(tlsChecks, TlsCheck{
+			CheckOptions:       check.checkOptions(),
+			Address:            check.Address,
+			CertFile:           check.CertFile,
+			ServerName:         check.ServerName,
+			CAFile:             check.CAFile,
+			ExpiryWarningDays:  check.ExpiryWarningDays,
+			ExpiryCriticalDays: check.ExpiryCriticalDays,
+		})
+	}
+	return tlsChecks
+}
+
+// BuildUdpChecks converts the declared UDP checks into UdpChecks, preserving the order in which they were declared. The
+// config must have been validated.
+func (config *Config) BuildUdpChecks() []UdpCheck {
+	var udpChecks []UdpCheck
+	for _, check := range config.Checks {
+		if check.Type != CheckTypeUdp {
+			continue
+		}
+		udpChecks = append(udpChecks, UdpCheck{
+			CheckOptions: check.checkOptions(),
+			Probe:        check.probe(),
+			Address:      check.Address,
+		})
+	}
+	return udpChecks
+}
+
+// validate verifies that the assertion has a valid path and at least one valid comparison.
+func (assertion JsonAssertionConfig) validate() error {
+	if assertion.Path == "" {
+		return errors.New("path is required")
+	}
+	if _, err := SplitJsonPath(assertion.Path); err != nil {
+		return err
+	}
+	if _, err := regexp.Compile(assertion.Regex); err != nil {
+		return fmt.Errorf("invalid regex for %s: %w", assertion.Path, err)
+	}
+	if assertion.Min != nil && assertion.Max != nil && *assertion.Min > *assertion.Max {
+		return fmt.Errorf("min is greater than max for %s", assertion.Path)
+	}
+
+	compares := assertion.Equals != nil || assertion.Regex != "" || assertion.Min != nil || assertion.Max != nil
+	switch {
+	case !compares && assertion.Exists == nil:
+		return fmt.Errorf("no comparison for %s, set one of equals, regex, min, max or exists", assertion.Path)
+	case compares && assertion.Exists != nil && !*assertion.Exists:
+		return fmt.Errorf("%s can't be compared if it must not exist", assertion.Path)
+	}
+	return nil
+}
+
+// validate verifies that the assertion has a valid header name and at least one valid comparison.
+func (assertion HeaderAssertionConfig) validate() error {
+	if !httpTokenPattern.MatchString(assertion.Name) {
+		return fmt.Errorf("invalid header name %q", assertion.Name)
+	}
+	if _, err := regexp.Compile(assertion.Regex); err != nil {
+		return fmt.Errorf("invalid regex for %s: %w", assertion.Name, err)
+	}
+
+	compares := assertion.Equals != nil || assertion.Regex != ""
+	switch {
+	case !compares && assertion.Exists == nil:
+		return fmt.Errorf("no comparison for %s, set one of equals, regex or exists", assertion.Name)
+	case compares && assertion.Exists != nil && !*assertion.Exists:
+		return fmt.Errorf("%s can't be compared if it must not exist", assertion.Name)
+	}
+	return nil
+}
+
+// commonCheckFields are the keys of CheckConfig that apply to checks of every type.
+var commonCheckFields = []string{"name", "type", "timeout", "interval", "failure_threshold", "success_threshold", "severity"}
+
+// checkTypeFields are the keys of CheckConfig that apply to each check type, in addition to the commonCheckFields.
+var checkTypeFields = map[string][]string{
+	CheckTypeTcp:    {"address", "send", "send_hex", "expect", "expect_hex", "read_timeout_ms", "max_bytes"},
+	CheckTypeScript: {"command", "mode"},
+	CheckTypeHttp: {
+		"url", "verify_payload", "method", "headers", "body", "user_agent", "host", "expected_status", "follow_redirects",
+		"max_redirects", "final_url_regex", "json_assertions", "header_assertions", "latency_warning_ms",
+		"latency_critical_ms", "server_name", "ca_file", "client_cert_file", "client_key_file", "min_tls_version",
+		"pinned_fingerprints",
+	},
+	CheckTypeDns: {"host", "resolver", "record_type", "answers", "answer_regex", "max_duration_ms"},
+	CheckTypeTls: {"address", "cert_file", "server_name", "ca_file", "expiry_warning_days", "expiry_critical_days"},
+	CheckTypeUdp: {"address", "send", "send_hex", "expect", "expect_hex"},
+}
+
+// validateFields verifies that the check doesn't set any key that only applies to other check types, since the
+// setting would be silently ignored otherwise. The check must have a known type.
+func (check CheckConfig) validateFields() error {
+	value := reflect.ValueOf(check)
+	for i := range value.NumField() {
+		key, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("yaml"), ",")
+		if value.Field(i).IsZero() || slices.Contains(commonCheckFields, key) || slices.Contains(checkTypeFields[check.Type], key) {
+			continue
+		}
+		return fmt.Errorf("check %q of type %s doesn't support %s", check.Name, check.Type, key)
+	}
+	return nil
+}
+
+// validateProbe verifies the payload and expected response of the check.
+func (check CheckConfig) validateProbe() error {
+	if check.Send != "" && check.SendHex != "" {
+		return fmt.Errorf("check %q can't set both send and send_hex", check.Name)
+	}
+	if _, err := decodeHex(check.SendHex); err != nil {
+		return fmt.Errorf("check %q has an invalid send_hex: %w", check.Name, err)
+	}
+	if _, err := regexp.Compile(check.Expect); err != nil {
+		return fmt.Errorf("check %q has an invalid expect: %w", check.Name, err)
+	}
+	if _, err := decodeHex(check.ExpectHex); err != nil {
+		return fmt.Errorf("check %q has an invalid expect_hex: %w", check.Name, err)
+	}
+	return nil
+}
+
+// probe returns the payload and expected response of the check. The check must have been validated.
+func (check CheckConfig) probe() Probe {
+	probe := Probe{Expect: check.Expect}
+	probe.Send, _ = decodeHex(check.SendHex)
+	if check.Send != "" {
+		probe.Send = []byte(check.Send)
+	}
+	probe.ExpectBytes, _ = decodeHex(check.ExpectHex)
+	return probe
+}
+
+// decodeHex decodes a hex string such as "de ad be ef" or "de:ad:be:ef". Spaces and colons between the bytes are
+// ignored.
+func decodeHex(value string) ([]byte, error) {
+	value = strings.NewReplacer(" ", "", ":", "", "\t", "", "\n", "").Replace(value)
+	if value == "" {
+		return nil, nil
+	}
+	return hex.DecodeString(value)
+}
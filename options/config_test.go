@@ -0,0 +1,625 @@
+package options
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	dummyScriptPath := filepath.ToSlash(filepath.Join(tmpDir, "dummy_script.sh"))
+	err := os.WriteFile(dummyScriptPath, []byte("echo hello"), 0755)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		fileName    string
+		content     string
+		expected    *Config
+		expectedErr string
+	}{
+		{
+			name:     "YAML config",
+			fileName: "config.yaml",
+			content: `
+log_level: debug
+singleflight: true
+listener:
+  address: 127.0.0.1:6000
+  write_timeout: 20
+  tls_cert: /etc/tls.crt
+  tls_key: /etc/tls.key
+timeouts:
+  script: 10
+checks:
+  - name: app-port
+    type: tcp
+    address: "8080"
+  - name: db
+    type: script
+    command: "` + dummyScriptPath + ` --fast"
+    timeout: 30
+    mode: nagios
+  - name: api
+    type: http
+    url: http://localhost:8080/health
+    verify_payload: READY
+`,
+			expected: &Config{
+				LogLevel:     "debug",
+				Singleflight: true,
+				Listener:     ListenerConfig{Address: "127.0.0.1:6000", WriteTimeout: 20, TLSCert: "/etc/tls.crt", TLSKey: "/etc/tls.key"},
+				Timeouts:     TimeoutConfig{Script: 10},
+				Checks: []CheckConfig{
+					{Name: "app-port", Type: CheckTypeTcp, Address: "8080"},
+					{Name: "db", Type: CheckTypeScript, Command: dummyScriptPath + " --fast", Timeout: 30, Mode: ScriptModeNagios},
+					{Name: "api", Type: CheckTypeHttp, Url: "http://localhost:8080/health", VerifyPayload: "READY"},
+				},
+			},
+		},
+		{
+			name:     "JSON config",
+			fileName: "config.json",
+			content:  `{"detailed_status": true, "metrics": true, "timeouts": {"tcp_dial": 2}, "checks": [{"name": "app-port", "type": "tcp", "address": "8080"}]}`,
+			expected: &Config{
+				DetailedStatus: true,
+				Metrics:        true,
+				Timeouts:       TimeoutConfig{TcpDial: 2},
+				Checks:         []CheckConfig{{Name: "app-port", Type: CheckTypeTcp, Address: "8080"}},
+			},
+		},
+		{
+			name:     "Empty YAML config",
+			fileName: "empty.yaml",
+			content:  "",
+			expected: &Config{},
+		},
+		{
+			name:        "Unknown key rejected",
+			fileName:    "typo.yaml",
+			content:     "singelflight: true\n",
+			expectedErr: "field singelflight not found",
+		},
+		{
+			name:        "Unknown JSON key rejected",
+			fileName:    "typo.json",
+			content:     `{"singelflight": true}`,
+			expectedErr: "unknown field",
+		},
+		{
+			name:        "Key of another check type rejected",
+			fileName:    "foreign.yaml",
+			content:     "checks:\n  - name: a\n    type: tcp\n    address: \"8080\"\n    url: http://localhost\n    mode: nagios\n",
+			expectedErr: `check "a" of type tcp doesn't support mode`,
+		},
+		{
+			name:        "Disabled redirects on a TLS check rejected",
+			fileName:    "redirects.yaml",
+			content:     "checks:\n  - name: a\n    type: tls\n    address: example.com:443\n    follow_redirects: false\n",
+			expectedErr: `check "a" of type tls doesn't support follow_redirects`,
+		},
+		{
+			name:        "Missing name",
+			fileName:    "noname.yaml",
+			content:     "checks:\n  - type: tcp\n    address: \"8080\"\n",
+			expectedErr: "check #1 is missing a name",
+		},
+		{
+			name:        "Duplicate name",
+			fileName:    "duplicate.yaml",
+			content:     "checks:\n  - {name: a, type: tcp, address: \"8080\"}\n  - {name: a, type: tcp, address: \"8081\"}\n",
+			expectedErr: `check name "a" is used more than once`,
+		},
+		{
+			name:        "Unknown type",
+			fileName:    "type.yaml",
+			content:     "checks:\n  - {name: a, type: carrier-pigeon}\n",
+			expectedErr: `unknown type "carrier-pigeon"`,
+		},
+		{
+			name:        "Negative timeout",
+			fileName:    "timeout.yaml",
+			content:     "checks:\n  - {name: a, type: tcp, address: \"8080\", timeout: -1}\n",
+			expectedErr: "negative timeout",
+		},
+		{
+			name:        "Negative interval",
+			fileName:    "interval.yaml",
+			content:     "checks:\n  - {name: a, type: tcp, address: \"8080\", interval: -5}\n",
+			expectedErr: "negative interval",
+		},
+		{
+			name:        "Negative threshold",
+			fileName:    "threshold.yaml",
+			content:     "checks:\n  - {name: a, type: tcp, address: \"8080\", failure_threshold: -1}\n",
+			expectedErr: "negative threshold",
+		},
+		{
+			name:        "Unknown severity",
+			fileName:    "severity.yaml",
+			content:     "checks:\n  - {name: a, type: tcp, address: \"8080\", severity: apocalyptic}\n",
+			expectedErr: `unknown severity "apocalyptic"`,
+		},
+		{
+			name:        "Invalid degraded status",
+			fileName:    "degraded.yaml",
+			content:     "degraded_status: 1000\n",
+			expectedErr: "invalid degraded_status 1000",
+		},
+		{
+			name:        "DNS check without host",
+			fileName:    "dns-host.yaml",
+			content:     "checks:\n  - name: a\n    type: dns\n",
+			expectedErr: `check "a" of type dns requires a host`,
+		},
+		{
+			name:        "Unknown DNS record type",
+			fileName:    "dns-type.yaml",
+			content:     "checks:\n  - name: a\n    type: dns\n    host: example.com\n    record_type: SOA\n",
+			expectedErr: `check "a" has unknown record_type "SOA"`,
+		},
+		{
+			name:        "Invalid DNS answer regex",
+			fileName:    "dns-regex.yaml",
+			content:     "checks:\n  - name: a\n    type: dns\n    host: example.com\n    answer_regex: \"[\"\n",
+			expectedErr: `check "a" has an invalid answer_regex`,
+		},
+		{
+			name:        "TLS check without a target",
+			fileName:    "tls-target.yaml",
+			content:     "checks:\n  - name: a\n    type: tls\n",
+			expectedErr: `check "a" of type tls requires either an address or a cert_file`,
+		},
+		{
+			name:        "TLS check with two targets",
+			fileName:    "tls-targets.yaml",
+			content:     "checks:\n  - name: a\n    type: tls\n    address: example.com:443\n    cert_file: /etc/ssl/app.crt\n",
+			expectedErr: `check "a" of type tls requires either an address or a cert_file`,
+		},
+		{
+			name:        "Negative TLS expiry window",
+			fileName:    "tls-expiry.yaml",
+			content:     "checks:\n  - name: a\n    type: tls\n    address: example.com\n    expiry_warning_days: -1\n",
+			expectedErr: `check "a" has a negative expiry window`,
+		},
+		{
+			name:        "Invalid HTTP method",
+			fileName:    "http-method.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    method: \"GET /\"\n",
+			expectedErr: `check "a" has an invalid method "GET /"`,
+		},
+		{
+			name:        "Invalid HTTP header name",
+			fileName:    "http-header.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    headers:\n      - {name: \"X Api Key\", value: k3y}\n",
+			expectedErr: `check "a" has a header with an invalid name "X Api Key"`,
+		},
+		{
+			name:        "HTTP header with two sources",
+			fileName:    "http-header-sources.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    headers:\n      - {name: X-Api-Key, value_file: /run/secrets/key, value_env: API_KEY}\n",
+			expectedErr: `check "a" can't set both value_file and value_env for header X-Api-Key`,
+		},
+		{
+			name:        "Invalid HTTP expected status",
+			fileName:    "http-status.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    expected_status: \"200,3xx\"\n",
+			expectedErr: `check "a" has an invalid expected_status: invalid status code or range "3xx"`,
+		},
+		{
+			name:        "Negative HTTP max redirects",
+			fileName:    "http-redirects.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    max_redirects: -1\n",
+			expectedErr: `check "a" has a negative max_redirects`,
+		},
+		{
+			name:        "Invalid HTTP final URL regex",
+			fileName:    "http-final-url.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    final_url_regex: \"[\"\n",
+			expectedErr: `check "a" has an invalid final_url_regex`,
+		},
+		{
+			name:        "JSON assertion without path",
+			fileName:    "json-path.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    json_assertions:\n      - {equals: UP}\n",
+			expectedErr: `check "a" has an invalid JSON assertion: path is required`,
+		},
+		{
+			name:        "JSON assertion with empty key",
+			fileName:    "json-key.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    json_assertions:\n      - {path: components..status, equals: UP}\n",
+			expectedErr: `check "a" has an invalid JSON assertion: JSON path "components..status" has an empty key`,
+		},
+		{
+			name:        "JSON assertion without comparison",
+			fileName:    "json-comparison.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    json_assertions:\n      - {path: status}\n",
+			expectedErr: `check "a" has an invalid JSON assertion: no comparison for status`,
+		},
+		{
+			name:        "JSON assertion with invalid regex",
+			fileName:    "json-regex.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    json_assertions:\n      - {path: status, regex: \"(\"}\n",
+			expectedErr: `check "a" has an invalid JSON assertion: invalid regex for status`,
+		},
+		{
+			name:        "JSON assertion with inverted bounds",
+			fileName:    "json-bounds.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    json_assertions:\n      - {path: free, min: 10, max: 1}\n",
+			expectedErr: `check "a" has an invalid JSON assertion: min is greater than max for free`,
+		},
+		{
+			name:        "JSON assertion comparing an absent path",
+			fileName:    "json-absent.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    json_assertions:\n      - {path: error, exists: false, equals: \"null\"}\n",
+			expectedErr: `check "a" has an invalid JSON assertion: error can't be compared if it must not exist`,
+		},
+		{
+			name:        "Header assertion with invalid name",
+			fileName:    "header-name.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    header_assertions:\n      - {name: \"Content Type\", exists: true}\n",
+			expectedErr: `check "a" has an invalid header assertion: invalid header name "Content Type"`,
+		},
+		{
+			name:        "Header assertion without comparison",
+			fileName:    "header-comparison.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    header_assertions:\n      - {name: X-Version}\n",
+			expectedErr: `check "a" has an invalid header assertion: no comparison for X-Version`,
+		},
+		{
+			name:        "Header assertion comparing an absent header",
+			fileName:    "header-absent.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    header_assertions:\n      - {name: Server, exists: false, regex: nginx}\n",
+			expectedErr: `check "a" has an invalid header assertion: Server can't be compared if it must not exist`,
+		},
+		{
+			name:        "Negative latency budget",
+			fileName:    "latency.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: http://localhost\n    latency_warning_ms: -1\n",
+			expectedErr: `check "a" has a negative latency budget`,
+		},
+		{
+			name:        "Client certificate without key",
+			fileName:    "client-cert.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: https://localhost\n    client_cert_file: /etc/ssl/client.crt\n",
+			expectedErr: `check "a" must set both client_cert_file and client_key_file, or neither`,
+		},
+		{
+			name:        "Unknown TLS version",
+			fileName:    "min-tls-version.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: https://localhost\n    min_tls_version: \"1.4\"\n",
+			expectedErr: `check "a" has unknown min_tls_version "1.4"`,
+		},
+		{
+			name:        "Pinned fingerprint of the wrong length",
+			fileName:    "pinned-fingerprint.yaml",
+			content:     "checks:\n  - name: a\n    type: http\n    url: https://localhost\n    pinned_fingerprints: [\"AB:CD\"]\n",
+			expectedErr: `check "a" has an invalid pinned fingerprint "AB:CD"`,
+		},
+		{
+			name:        "Invalid TCP expected response",
+			fileName:    "tcp-expect.yaml",
+			content:     "checks:\n  - name: a\n    type: tcp\n    address: \"25\"\n    expect: \"(\"\n",
+			expectedErr: `check "a" has an invalid expect`,
+		},
+		{
+			name:        "Negative TCP read timeout",
+			fileName:    "tcp-read-timeout.yaml",
+			content:     "checks:\n  - name: a\n    type: tcp\n    address: \"25\"\n    read_timeout_ms: -1\n",
+			expectedErr: `check "a" has a negative read_timeout_ms`,
+		},
+		{
+			name:        "Negative TCP max bytes",
+			fileName:    "tcp-max-bytes.yaml",
+			content:     "checks:\n  - name: a\n    type: tcp\n    address: \"25\"\n    max_bytes: -1\n",
+			expectedErr: `check "a" has a negative max_bytes`,
+		},
+		{
+			name:        "UDP check without address",
+			fileName:    "udp-address.yaml",
+			content:     "checks:\n  - name: a\n    type: udp\n    send: ping\n",
+			expectedErr: `check "a" of type udp requires an address`,
+		},
+		{
+			name:        "UDP check with two payloads",
+			fileName:    "udp-send.yaml",
+			content:     "checks:\n  - name: a\n    type: udp\n    address: \"8125\"\n    send: ping\n    send_hex: \"00\"\n",
+			expectedErr: `check "a" can't set both send and send_hex`,
+		},
+		{
+			name:        "Invalid UDP payload",
+			fileName:    "udp-hex.yaml",
+			content:     "checks:\n  - name: a\n    type: udp\n    address: \"8125\"\n    send_hex: xyz\n",
+			expectedErr: `check "a" has an invalid send_hex`,
+		},
+		{
+			name:        "Invalid UDP expected response",
+			fileName:    "udp-expect.yaml",
+			content:     "checks:\n  - name: a\n    type: udp\n    address: \"8125\"\n    expect: \"(\"\n",
+			expectedErr: `check "a" has an invalid expect`,
+		},
+		{
+			name:        "Invalid UDP expected bytes",
+			fileName:    "udp-expect-hex.yaml",
+			content:     "checks:\n  - name: a\n    type: udp\n    address: \"8125\"\n    expect_hex: \"0\"\n",
+			expectedErr: `check "a" has an invalid expect_hex`,
+		},
+		{
+			name:        "Unknown script mode",
+			fileName:    "mode.yaml",
+			content:     "checks:\n  - name: a\n    type: script\n    command: /bin/true\n    mode: icinga\n",
+			expectedErr: `check "a" has unknown mode "icinga"`,
+		},
+		{
+			name:        "Invalid maintenance status",
+			fileName:    "maintenance.yaml",
+			content:     "maintenance:\n  status: 42\n",
+			expectedErr: "invalid maintenance status 42",
+		},
+		{
+			name:        "Negative drain period",
+			fileName:    "drain.yaml",
+			content:     "listener:\n  drain_period: -1\n",
+			expectedErr: "negative listener drain_period",
+		},
+		{
+			name:        "Invalid password hash",
+			fileName:    "hash.yaml",
+			content:     "auth:\n  basic:\n    - {username: ops, password_bcrypt: s3cret}\n",
+			expectedErr: `auth basic credential "ops" must have a password_bcrypt, as generated by htpasswd -nbB`,
+		},
+		{
+			name:        "Invalid allowed CIDR",
+			fileName:    "cidr.yaml",
+			content:     "auth:\n  allowed_cidrs: [10.0.0.0/33]\n",
+			expectedErr: `auth allowed_cidrs: invalid CIDR "10.0.0.0/33"`,
+		},
+		{
+			name:        "Empty bearer token",
+			fileName:    "token.yaml",
+			content:     "auth:\n  bearer_tokens: [\"\"]\n",
+			expectedErr: "auth bearer token #1 is empty",
+		},
+		{
+			name:        "Endpoint path without slash",
+			fileName:    "endpoint.yaml",
+			content:     "endpoints:\n  - path: livez\n",
+			expectedErr: "endpoint #1 must have a path starting with /",
+		},
+		{
+			name:        "Endpoint path with a wildcard",
+			fileName:    "wildcard.yaml",
+			content:     "endpoints:\n  - path: \"/ready{x\"\n",
+			expectedErr: `endpoint path "/ready{x" must be a literal path`,
+		},
+		{
+			name:        "Endpoint path with a method",
+			fileName:    "method.yaml",
+			content:     "endpoints:\n  - path: \"/ GET /ready\"\n",
+			expectedErr: `endpoint path "/ GET /ready" must be a literal path`,
+		},
+		{
+			name:        "Duplicate endpoint path",
+			fileName:    "endpoints.yaml",
+			content:     "endpoints:\n  - path: /livez\n  - path: /livez\n",
+			expectedErr: `endpoint path "/livez" is used more than once`,
+		},
+		{
+			name:        "Role endpoints default their path",
+			fileName:    "roles.yaml",
+			content:     "endpoints:\n  - role: liveness\n  - role: readiness\n    path: /livez\n",
+			expectedErr: `endpoint path "/livez" is used more than once`,
+		},
+		{
+			name:        "Unknown endpoint role",
+			fileName:    "role.yaml",
+			content:     "endpoints:\n  - role: lunch\n",
+			expectedErr: `unknown role "lunch"`,
+		},
+		{
+			name:        "Invalid failure status",
+			fileName:    "status.yaml",
+			content:     "endpoints:\n  - path: /\n    failure_status: 42\n",
+			expectedErr: "invalid failure_status 42",
+		},
+		{
+			name:        "Missing target",
+			fileName:    "target.yaml",
+			content:     "checks:\n  - {name: a, type: http}\n",
+			expectedErr: "requires a url",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, tc.fileName)
+			err := os.WriteFile(path, []byte(tc.content), 0644)
+			assert.NoError(t, err)
+
+			actual, err := LoadConfig(path)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "does-not-exist.yaml"))
+	assert.ErrorContains(t, err, "failed to read config file")
+}
+
+func TestConfigBuildChecks(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	dummyScriptPath := filepath.ToSlash(filepath.Join(tmpDir, "dummy_script.sh"))
+	err := os.WriteFile(dummyScriptPath, []byte("echo hello"), 0755)
+	assert.NoError(t, err)
+
+	no := false
+	up := "UP"
+	minFree := 1073741824.0
+	fingerprint := sha256.Sum256([]byte("test"))
+	config := &Config{Checks: []CheckConfig{
+		{Name: "app-port", Type: CheckTypeTcp, Address: "8080", FailureThreshold: 3, SuccessThreshold: 2},
+		{Name: "redis", Type: CheckTypeTcp, Address: "6379", Send: "PING\r\n", Expect: `^\+PONG`, ReadTimeoutMs: 500, MaxBytes: 64},
+		{Name: "db", Type: CheckTypeScript, Command: dummyScriptPath + " --fast", Timeout: 30, Mode: ScriptModeNagios},
+		{Name: "api", Type: CheckTypeHttp, Url: "http://localhost:8080/health", VerifyPayload: "READY", Severity: SeverityWarning},
+		{Name: "deep", Type: CheckTypeHttp, Url: "https://10.0.0.5/health", Method: "post", Body: "{}", Host: "api.internal", UserAgent: "lb-probe", Headers: []HeaderConfig{
+			{Name: "Content-Type", Value: "application/json"},
+			{Name: "Authorization", Value: "Bearer ", ValueFile: "/run/secrets/token"},
+		}},
+		{Name: "sso", Type: CheckTypeHttp, Url: "http://localhost:8080/", ExpectedStatus: "200,301-302", FollowRedirects: &no},
+		{Name: "login", Type: CheckTypeHttp, Url: "http://localhost:8080/login", MaxRedirects: 2, FinalUrlRegex: "/sso/"},
+		{Name: "actuator", Type: CheckTypeHttp, Url: "http://localhost:8080/actuator/health", JsonAssertions: []JsonAssertionConfig{
+			{Path: "status", Equals: &up},
+			{Path: "components.diskSpace.details.free", Min: &minFree},
+			{Path: "components.redis", Exists: &no},
+		}},
+		{Name: "cdn", Type: CheckTypeHttp, Url: "https://localhost/", LatencyWarningMs: 200, LatencyCriticalMs: 1000, HeaderAssertions: []HeaderAssertionConfig{
+			{Name: "Cache-Control", Regex: "max-age"},
+			{Name: "Server", Exists: &no},
+		}},
+		{Name: "mtls", Type: CheckTypeHttp, Url: "https://10.0.0.5/health", ServerName: "api.internal", CAFile: "/etc/ssl/internal-ca.crt",
+			ClientCertFile: "/etc/ssl/client.crt", ClientKeyFile: "/etc/ssl/client.key", MinTlsVersion: "1.3",
+			PinnedFingerprints: []string{"9F:86:D0:81:88:4C:7D:65:9A:2F:EA:A0:C5:5A:D0:15:A3:BF:4F:1B:2B:0B:82:2C:D1:5D:6C:15:B0:F0:0A:08"}},
+	}}
+
+	ports, scripts, httpChecks, err := config.BuildChecks()
+	assert.NoError(t, err)
+	assert.Equal(t, []PortCheck{
+		{CheckOptions: CheckOptions{CheckName: "app-port", FailureThreshold: 3, SuccessThreshold: 2}, Address: "8080"},
+		{CheckOptions: CheckOptions{CheckName: "redis"}, Probe: Probe{Send: []byte("PING\r\n"), Expect: `^\+PONG`}, Address: "6379", ReadTimeoutMs: 500, MaxBytes: 64},
+	}, ports)
+	assert.Equal(t, []Script{{CheckOptions: CheckOptions{CheckName: "db", Timeout: 30}, Name: dummyScriptPath, Args: []string{"--fast"}, Mode: ScriptModeNagios}}, scripts)
+	assert.Equal(t, []HttpCheck{
+		{CheckOptions: CheckOptions{CheckName: "api", Severity: SeverityWarning}, Url: "http://localhost:8080/health", VerifyPayload: "READY"},
+		{CheckOptions: CheckOptions{CheckName: "deep"}, Url: "https://10.0.0.5/health", Method: "POST", Body: "{}", Host: "api.internal", UserAgent: "lb-probe", Headers: []HttpHeader{
+			{Name: "Content-Type", Value: "application/json"},
+			{Name: "Authorization", Value: "Bearer ", ValueFile: "/run/secrets/token"},
+		}},
+		{CheckOptions: CheckOptions{CheckName: "sso"}, Url: "http://localhost:8080/", ExpectedStatus: StatusRanges{{200, 200}, {301, 302}}, NoRedirects: true},
+		{CheckOptions: CheckOptions{CheckName: "login"}, Url: "http://localhost:8080/login", MaxRedirects: 2, FinalUrlRegex: "/sso/"},
+		{CheckOptions: CheckOptions{CheckName: "actuator"}, Url: "http://localhost:8080/actuator/health", JsonAssertions: []JsonAssertion{
+			{Path: "status", Equals: &up},
+			{Path: "components.diskSpace.details.free", Min: &minFree},
+			{Path: "components.redis", Exists: &no},
+		}},
+		{CheckOptions: CheckOptions{CheckName: "cdn"}, Url: "https://localhost/", LatencyWarningMs: 200, LatencyCriticalMs: 1000, HeaderAssertions: []HeaderAssertion{
+			{Name: "Cache-Control", Regex: "max-age"},
+			{Name: "Server", Exists: &no},
+		}},
+		{CheckOptions: CheckOptions{CheckName: "mtls"}, Url: "https://10.0.0.5/health", ServerName: "api.internal", CAFile: "/etc/ssl/internal-ca.crt",
+			ClientCertFile: "/etc/ssl/client.crt", ClientKeyFile: "/etc/ssl/client.key", MinTlsVersion: tls.VersionTLS13,
+			PinnedFingerprints: [][]byte{fingerprint[:]}},
+	}, httpChecks)
+
+	config.Checks = append(config.Checks, CheckConfig{Name: "bad", Type: CheckTypeScript, Command: "lskdf_non_existent_binary"})
+	_, _, _, err = config.BuildChecks()
+	assert.ErrorContains(t, err, `check "bad"`)
+}
+
+func TestConfigBuildDnsChecks(t *testing.T) {
+	config := &Config{Checks: []CheckConfig{
+		{Name: "app-port", Type: CheckTypeTcp, Address: "8080"},
+		{Name: "internal-dns", Type: CheckTypeDns, Host: "app.internal", Resolver: "10.0.0.2", RecordType: "a", Answers: []string{"10.0.0.10"}, MaxDurationMs: 50, Severity: SeverityWarning},
+		{Name: "spf", Type: CheckTypeDns, Host: "example.com", RecordType: "TXT", AnswerRegex: "^v=spf1 "},
+	}}
+	assert.NoError(t, config.validate())
+
+	assert.Equal(t, []DnsCheck{
+		{CheckOptions: CheckOptions{CheckName: "internal-dns", Severity: SeverityWarning}, Host: "app.internal", Resolver: "10.0.0.2", RecordType: "A", Answers: []string{"10.0.0.10"}, MaxDurationMs: 50},
+		{CheckOptions: CheckOptions{CheckName: "spf"}, Host: "example.com", RecordType: "TXT", AnswerRegex: "^v=spf1 "},
+	}, config.BuildDnsChecks())
+}
+
+func TestConfigBuildTlsChecks(t *testing.T) {
+	config := &Config{Checks: []CheckConfig{
+		{Name: "app-port", Type: CheckTypeTcp, Address: "8080"},
+		{Name: "public-cert", Type: CheckTypeTls, Address: "example.com:443", ServerName: "www.example.com", ExpiryWarningDays: 30, ExpiryCriticalDays: 7},
+		{Name: "local-cert", Type: CheckTypeTls, CertFile: "/etc/ssl/app.crt", CAFile: "/etc/ssl/ca.crt", Severity: SeverityWarning},
+	}}
+	assert.NoError(t, config.validate())
+
+	assert.Equal(t, []TlsCheck{
+		{CheckOptions: CheckOptions{CheckName: "public-cert"}, Address: "example.com:443", ServerName: "www.example.com", ExpiryWarningDays: 30, ExpiryCriticalDays: 7},
+		{CheckOptions: CheckOptions{CheckName: "local-cert", Severity: SeverityWarning}, CertFile: "/etc/ssl/app.crt", CAFile: "/etc/ssl/ca.crt"},
+	}, config.BuildTlsChecks())
+}
+
+func TestConfigBuildUdpChecks(t *testing.T) {
+	config := &Config{Checks: []CheckConfig{
+		{Name: "app-port", Type: CheckTypeTcp, Address: "8080"},
+		{Name: "statsd", Type: CheckTypeUdp, Address: "8125"},
+		{Name: "syslog", Type: CheckTypeUdp, Address: "localhost:514", Send: "ping", Expect: "^pong$", Timeout: 2},
+		{Name: "dns-forwarder", Type: CheckTypeUdp, Address: "10.0.0.2:53", SendHex: "12 34 01 00", ExpectHex: "12:34"},
+	}}
+	assert.NoError(t, config.validate())
+
+	assert.Equal(t, []UdpCheck{
+		{CheckOptions: CheckOptions{CheckName: "statsd"}, Address: "8125"},
+		{CheckOptions: CheckOptions{CheckName: "syslog", Timeout: 2}, Probe: Probe{Send: []byte("ping"), Expect: "^pong$"}, Address: "localhost:514"},
+		{CheckOptions: CheckOptions{CheckName: "dns-forwarder"}, Probe: Probe{Send: []byte{0x12, 0x34, 0x01, 0x00}, ExpectBytes: []byte{0x12, 0x34}}, Address: "10.0.0.2:53"},
+	}, config.BuildUdpChecks())
+}
+
+func TestConfigBuildAuth(t *testing.T) {
+	hash := "$2y$10$eIUL7/HCR2Ct8hb70UFsR.df62GfFi2hgt3XF8xQzNJxlaAgacaUW"
+	config := &Config{Auth: AuthConfig{
+		BearerTokens: []string{"token"},
+		Basic:        []BasicAuthConfig{{Username: "ops", PasswordBcrypt: hash}},
+		AllowedCidrs: []string{"10.0.0.0/8"},
+	}}
+	assert.NoError(t, config.validate())
+
+	assert.Equal(t, AuthOptions{
+		BearerTokens:     []string{"token"},
+		BasicCredentials: []BasicCredential{{Username: "ops", PasswordBcrypt: hash}},
+		AllowedCIDRs:     []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	}, config.BuildAuth())
+	assert.False(t, (&Config{}).BuildAuth().Enabled())
+}
+
+func TestConfigBuildEndpoints(t *testing.T) {
+	runAll := true
+	config := &Config{Endpoints: []EndpointConfig{
+		{Path: "/"},
+		{Path: "/debug", RunAll: &runAll},
+		{Role: EndpointRoleLiveness, Checks: []string{"app"}},
+		{Role: EndpointRoleReadiness, Path: "/ready", FailureStatus: 503},
+	}}
+
+	assert.Equal(t, []Endpoint{
+		{Path: "/"},
+		{Path: "/debug", RunAll: true},
+		{Path: "/livez", Role: EndpointRoleLiveness, Checks: []string{"app"}},
+		{Path: "/ready", Role: EndpointRoleReadiness, FailureStatusCode: 503},
+	}, config.BuildEndpoints(false))
+	assert.Nil(t, (&Config{}).BuildEndpoints(true))
+}
+
+func TestCheckTypeFieldsCoverCheckConfig(t *testing.T) {
+	// Every key of CheckConfig must apply to at least one check type, or it could never be set
+	fields := reflect.TypeFor[CheckConfig]()
+	for i := range fields.NumField() {
+		key, _, _ := strings.Cut(fields.Field(i).Tag.Get("yaml"), ",")
+		supported := slices.Contains(commonCheckFields, key)
+		for _, typeFields := range checkTypeFields {
+			supported = supported || slices.Contains(typeFields, key)
+		}
+		assert.True(t, supported, "key %s doesn't apply to any check type", key)
+	}
+}
@@ -1,10 +1,14 @@
 package options
 
 import (
+	"crypto/tls"
 	"encoding/csv"
 	"fmt"
+	"net/http"
+	"net/netip"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -14,9 +18,12 @@ import (
 // It maps the command-line flags into an internal structured format passed directly
 // to the server subsystems, decoupling the HTTP/TCP execution logic from the CLI framework.
 type Options struct {
-	Ports            []string
+	Ports            []PortCheck
 	Scripts          []Script
 	HttpChecks       []HttpCheck
+	DnsChecks        []DnsCheck
+	TlsChecks        []TlsCheck
+	UdpChecks        []UdpCheck
 	ScriptTimeout    int
 	HttpReadTimeout  int
 	HttpWriteTimeout int
@@ -24,20 +31,603 @@ type Options struct {
 	TcpDialTimeout   int
 	HttpDialTimeout  int
 	Singleflight     bool
+	Background       bool
+	CheckInterval    int
+	CheckJitter      int
+	RunAll           bool
+	DegradedStatus   int
 	DetailedStatus   bool
+	Metrics          bool
 	AllowInsecureTLS bool
 	Listener         string
+	DrainPeriod      int
+	Maintenance      MaintenanceOptions
+	Admin            bool
+	TLSCert          string
+	TLSKey           string
+	TLSClientCA      string
+	Endpoints        []Endpoint
+	Auth             AuthOptions
 	Logger           *logrus.Logger
 }
 
+// DefaultEndpointPath is the path served when no endpoints are configured. It matches every request path.
+const DefaultEndpointPath = "/"
+
+// MetricsPath is the path on which the Prometheus metrics are served when enabled. It cannot be used by an endpoint.
+const MetricsPath = "/metrics"
+
+// The roles an endpoint can play for an orchestrator such as Kubernetes. Each role has a conventional default path.
+const (
+	EndpointRoleLiveness  = "liveness"
+	EndpointRoleReadiness = "readiness"
+	EndpointRoleStartup   = "startup"
+)
+
+// DefaultEndpointPaths maps every endpoint role to the path it is served on unless configured otherwise.
+var DefaultEndpointPaths = map[string]string{
+	EndpointRoleLiveness:  "/livez",
+	EndpointRoleReadiness: "/readyz",
+	EndpointRoleStartup:   "/startupz",
+}
+
+// DefaultFailureStatusCode is the HTTP status code returned when a check fails, unless an endpoint overrides it.
+const DefaultFailureStatusCode = 504
+
+// DefaultDegradedStatusCode is the HTTP status code returned when only non-critical checks fail, unless configured
+// otherwise.
+const DefaultDegradedStatusCode = 200
+
+// Endpoint is an HTTP path on the listener that runs a set of checks when requested.
+type Endpoint struct {
+	Path string
+	// Role is one of the EndpointRole constants, or empty for a plain user-defined endpoint.
+	Role string
+	// Checks lists the labels of the checks this endpoint runs. When empty, it runs every configured check.
+	Checks []string
+	// FailureStatusCode is returned when a critical check fails. When 0, DefaultFailureStatusCode applies.
+	FailureStatusCode int
+	// DegradedStatusCode is returned when only non-critical checks fail. When 0, the global DegradedStatus applies.
+	DegradedStatusCode int
+	// RunAll disables early short-circuiting, so that every check runs to completion and all failures are reported.
+	RunAll bool
+}
+
+// Includes reports whether the endpoint runs the check with the given label.
+func (endpoint Endpoint) Includes(label string) bool {
+	if len(endpoint.Checks) == 0 {
+		return true
+	}
+	for _, name := range endpoint.Checks {
+		if name == label {
+			return true
+		}
+	}
+	return false
+}
+
+// GatesTraffic reports whether the endpoint tells load balancers whether to route traffic to the instance, and thus
+// fails while the server drains before shutting down or is in maintenance mode. This is the case for readiness
+// endpoints and for endpoints without a role, which is what load balancers usually probe, but not for liveness and
+// startup endpoints, so that orchestrators don't restart the instance in the meantime.
+func (endpoint Endpoint) GatesTraffic() bool {
+	return endpoint.Role == "" || endpoint.Role == EndpointRoleReadiness
+}
+
+// EffectiveFailureStatusCode returns the status code the endpoint reports when a check fails.
+func (endpoint Endpoint) EffectiveFailureStatusCode() int {
+	if endpoint.FailureStatusCode != 0 {
+		return endpoint.FailureStatusCode
+	}
+	return DefaultFailureStatusCode
+}
+
+// EffectiveDegradedStatusCode returns the status code the endpoint reports when only non-critical checks fail.
+func (endpoint Endpoint) EffectiveDegradedStatusCode(opts *Options) int {
+	if endpoint.DegradedStatusCode != 0 {
+		return endpoint.DegradedStatusCode
+	}
+	if opts.DegradedStatus != 0 {
+		return opts.DegradedStatus
+	}
+	return DefaultDegradedStatusCode
+}
+
+// EffectiveEndpoints returns the configured endpoints, or a single catch-all endpoint inheriting the global RunAll
+// setting if none were configured.
+func (opts *Options) EffectiveEndpoints() []Endpoint {
+	if len(opts.Endpoints) > 0 {
+		return opts.Endpoints
+	}
+	return []Endpoint{{Path: DefaultEndpointPath, RunAll: opts.RunAll}}
+}
+
+// MaintenanceOptions configures maintenance mode, in which the endpoints that gate traffic fail with StatusCode and
+// Message regardless of the checks. Maintenance mode is on while File exists, or when turned on with SIGUSR1 or the
+// admin endpoint.
+type MaintenanceOptions struct {
+	File       string
+	StatusCode int
+	Message    string
+}
+
+// DefaultMaintenanceStatusCode and DefaultMaintenanceMessage are returned in maintenance mode unless configured otherwise.
+const DefaultMaintenanceStatusCode = 503
+const DefaultMaintenanceMessage = "maintenance"
+
+// AdminMaintenancePath is the path of the admin endpoint toggling maintenance mode. It cannot be used by an endpoint
+// while the admin endpoints are enabled.
+const AdminMaintenancePath = "/admin/maintenance"
+
+// AuthOptions lists who may see the detailed status output. A request is authorized if it matches any of them. When
+// none are configured, the detailed output is served to every request, as it always was.
+type AuthOptions struct {
+	BearerTokens     []string
+	BasicCredentials []BasicCredential
+	AllowedCIDRs     []netip.Prefix
+}
+
+// BasicCredential is a user allowed to authenticate with HTTP basic auth. Only the bcrypt hash of the password is kept,
+// in the format used by htpasswd.
+type BasicCredential struct {
+	Username       string
+	PasswordBcrypt string
+}
+
+// Enabled reports whether the detailed status output is restricted at all.
+func (auth AuthOptions) Enabled() bool {
+	return len(auth.BearerTokens) > 0 || len(auth.BasicCredentials) > 0 || len(auth.AllowedCIDRs) > 0
+}
+
+// ParseCIDRs parses a list of CIDR ranges, such as 10.0.0.0/8, or single IP addresses.
+func ParseCIDRs(cidrs []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, cidr := range cidrs {
+		if addr, err := netip.ParseAddr(cidr); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// StatusRange is an inclusive range of HTTP status codes.
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+// StatusRanges is a list of HTTP status codes and ranges, such as 200,204,301-302.
+type StatusRanges []StatusRange
+
+// ParseStatusRanges parses a comma-separated list of HTTP status codes and ranges, such as "200,204,301-302,401".
+func ParseStatusRanges(value string) (StatusRanges, error) {
+	var ranges StatusRanges
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		minText, maxText, isRange := strings.Cut(part, "-")
+		if !isRange {
+			maxText = minText
+		}
+		statusRange := StatusRange{}
+		var err error
+		if statusRange.Min, err = parseStatusCode(minText); err == nil {
+			statusRange.Max, err = parseStatusCode(maxText)
+		}
+		if err != nil || statusRange.Min > statusRange.Max {
+			return nil, fmt.Errorf("invalid status code or range %q", part)
+		}
+		ranges = append(ranges, statusRange)
+	}
+	return ranges, nil
+}
+
+func parseStatusCode(value string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	if code < 100 || code > 599 {
+		return 0, fmt.Errorf("status code %d out of range", code)
+	}
+	return code, nil
+}
+
+// Contains returns whether the status code is in one of the ranges.
+func (ranges StatusRanges) Contains(code int) bool {
+	for _, statusRange := range ranges {
+		if code >= statusRange.Min && code <= statusRange.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the ranges in the format parsed by ParseStatusRanges.
+func (ranges StatusRanges) String() string {
+	var parts []string
+	for _, statusRange := range ranges {
+		if statusRange.Min == statusRange.Max {
+			parts = append(parts, strconv.Itoa(statusRange.Min))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", statusRange.Min, statusRange.Max))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// CheckOptions holds the settings that every check type carries in addition to its target.
+type CheckOptions struct {
+	// CheckName is the user-assigned, stable identifier of the check. When empty, the check's target is used instead.
+	CheckName string
+	// Timeout is the check's own timeout, in seconds. When 0, the global timeout for its check type applies.
+	Timeout int
+	// Interval is how often the check runs in background mode, in seconds, and the window in which runs count once
+	// towards the thresholds. When 0, the global interval applies.
+	Interval int
+	// FailureThreshold is the number of consecutive failures after which a healthy check is reported as failing, and
+	// SuccessThreshold the number of consecutive successes after which a failing check is reported as passing again.
+	// Both default to 1 when 0. Runs are counted at most once per Interval, however many endpoints request the check.
+	FailureThreshold int
+	SuccessThreshold int
+	// Severity is one of the Severity constants, and decides how a failure of the check affects the overall status.
+	// Defaults to SeverityCritical when empty.
+	Severity string
+}
+
+// The severities a check can have. A failing critical check fails the endpoint, a failing warning check only degrades
+// it, and an informational check is reported without affecting the endpoint's status at all.
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+// EffectiveSeverity returns the check's severity, defaulting to SeverityCritical.
+func (checkOptions CheckOptions) EffectiveSeverity() string {
+	if checkOptions.Severity == "" {
+		return SeverityCritical
+	}
+	return checkOptions.Severity
+}
+
+// labelOr returns the user-assigned check name, or the given target if the check was not given a name.
+func (checkOptions CheckOptions) labelOr(target string) string {
+	if checkOptions.CheckName != "" {
+		return checkOptions.CheckName
+	}
+	return target
+}
+
+type PortCheck struct {
+	CheckOptions
+	// Probe optionally sends a payload once connected and verifies the response, such as the banner of an SMTP, SSH or
+	// Redis server
+	Probe
+	Address string
+	// ReadTimeoutMs bounds how long to wait for the expected response, in milliseconds. The timeout of the check, which
+	// covers the dial and the exchange together, applies either way.
+	ReadTimeoutMs int
+	// MaxBytes is the most the check reads while waiting for the expected response. When 0, DefaultProbeMaxBytes
+	// applies.
+	MaxBytes int
+}
+
+// DefaultProbeMaxBytes is the most a TCP check reads while waiting for the expected response by default.
+const DefaultProbeMaxBytes = 4096
+
+// EffectiveMaxBytes returns the most the check reads while waiting for the expected response.
+func (port PortCheck) EffectiveMaxBytes() int {
+	if port.MaxBytes > 0 {
+		return port.MaxBytes
+	}
+	return DefaultProbeMaxBytes
+}
+
+// Label returns the name identifying the check in logs and detailed output.
+func (port PortCheck) Label() string {
+	return port.labelOr(port.Address)
+}
+
 type Script struct {
+	CheckOptions
 	Name string
 	Args []string
+	// Mode is one of the ScriptMode constants, and decides how the outcome of the script is interpreted. Defaults to
+	// ScriptModeExitCode when empty.
+	Mode string
+}
+
+// The ways a script can report its outcome. In exit_code mode any non-zero exit status fails the check. In nagios
+// mode the script follows the Nagios plugin convention: exit status 0 is OK, 1 WARNING, 2 CRITICAL and 3 UNKNOWN, and
+// the first line of output is the status text, optionally followed by performance data after a "|". In json mode the
+// script prints a JSON document with its status, a message, metrics and the results of sub-checks.
+const (
+	ScriptModeExitCode = "exit_code"
+	ScriptModeNagios   = "nagios"
+	ScriptModeJson     = "json"
+)
+
+// EffectiveMode returns the script's mode, defaulting to ScriptModeExitCode.
+func (script Script) EffectiveMode() string {
+	if script.Mode == "" {
+		return ScriptModeExitCode
+	}
+	return script.Mode
+}
+
+// String renders the script as the command line it was parsed from, which keeps log output readable.
+func (script Script) String() string {
+	return strings.Join(append([]string{script.Name}, script.Args...), " ")
+}
+
+// Label returns the name identifying the check in logs and detailed output.
+func (script Script) Label() string {
+	return script.labelOr(script.String())
 }
 
 type HttpCheck struct {
+	CheckOptions
 	Url           string
 	VerifyPayload string
+	// Method defaults to GET
+	Method  string
+	Headers []HttpHeader
+	Body    string
+	// Host overrides the Host header, and the server name sent as SNI and verified against the certificate
+	Host string
+	// UserAgent overrides the default User-Agent header of Go's HTTP client
+	UserAgent string
+	// ExpectedStatus lists the status codes a healthy response may have. When empty, any 2xx status passes.
+	ExpectedStatus StatusRanges
+	// NoRedirects checks the status of a redirect itself instead of following it. Otherwise at most MaxRedirects are
+	// followed, or DefaultMaxRedirects when 0.
+	NoRedirects  bool
+	MaxRedirects int
+	// FinalUrlRegex must match the URL of the final response, after following redirects
+	FinalUrlRegex string
+	// JsonAssertions must all hold for the response body, which then has to be a JSON document
+	JsonAssertions []JsonAssertion
+	// HeaderAssertions must all hold for the headers of the response
+	HeaderAssertions []HeaderAssertion
+	// LatencyWarningMs reports a warning, and LatencyCriticalMs fails the check, if the request took longer, in
+	// milliseconds. When 0, only the timeout applies.
+	LatencyWarningMs  int
+	LatencyCriticalMs int
+	// ServerName is sent as SNI and verified against the certificate, instead of the host of Host or Url
+	ServerName string
+	// CAFile is a PEM encoded bundle of the CAs the server's certificate is verified against. When empty, the system
+	// roots are used.
+	CAFile string
+	// ClientCertFile and ClientKeyFile are the PEM encoded certificate and key presented to servers that require mTLS
+	ClientCertFile string
+	ClientKeyFile  string
+	// MinTlsVersion is one of the TlsVersions. When 0, Go's default applies.
+	MinTlsVersion uint16
+	// PinnedFingerprints are SHA-256 fingerprints of certificates, one of which has to be in the chain the server
+	// presented or in the chain it was verified with
+	PinnedFingerprints [][]byte
+}
+
+// TlsVersions maps the TLS versions an HTTP check can require to their crypto/tls constants.
+var TlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// HeaderAssertion verifies a header of an HTTP response. Every comparison that is set must hold for at least one value
+// of the header, and the header has to be present for any of them to.
+type HeaderAssertion struct {
+	Name   string
+	Equals *string
+	Regex  string
+	// Exists asserts whether the header is present. When false, no other comparison may be set.
+	Exists *bool
+}
+
+// JsonAssertion verifies the value at Path in a JSON response. Every comparison that is set must hold, and the path has
+// to exist for any of them to.
+type JsonAssertion struct {
+	// Path is a gjson-style path: keys separated by dots, array indexes as numbers, and # for the length of an array,
+	// e.g. components.db.status or items.#. Dots within keys are escaped with a backslash.
+	Path string
+	// Equals is compared to the value as text. Numbers are compared numerically, and objects and arrays as compact JSON.
+	Equals *string
+	// Regex must match the value as text
+	Regex string
+	// Min and Max are inclusive bounds for a numeric value
+	Min *float64
+	Max *float64
+	// Exists asserts whether the path is present. When false, no other comparison may be set.
+	Exists *bool
+}
+
+// SplitJsonPath splits a JsonAssertion path into its keys, unescaping dots within keys.
+func SplitJsonPath(path string) ([]string, error) {
+	var keys []string
+	var key strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path):
+			i++
+			key.WriteByte(path[i])
+		case path[i] == '.':
+			keys = append(keys, key.String())
+			key.Reset()
+		default:
+			key.WriteByte(path[i])
+		}
+	}
+	keys = append(keys, key.String())
+
+	for _, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("JSON path %q has an empty key", path)
+		}
+	}
+	return keys, nil
+}
+
+// DefaultMaxRedirects is the most redirects an HTTP check follows by default. Unlike Go's HTTP client, which gives up on
+// the 10th redirect, the check follows all of them and only fails on the next one.
+const DefaultMaxRedirects = 10
+
+// EffectiveMaxRedirects returns the most redirects the check follows.
+func (httpCheck HttpCheck) EffectiveMaxRedirects() int {
+	switch {
+	case httpCheck.NoRedirects:
+		return 0
+	case httpCheck.MaxRedirects > 0:
+		return httpCheck.MaxRedirects
+	default:
+		return DefaultMaxRedirects
+	}
+}
+
+// EffectiveMethod returns the HTTP method of the check's request.
+func (httpCheck HttpCheck) EffectiveMethod() string {
+	if httpCheck.Method == "" {
+		return http.MethodGet
+	}
+	return httpCheck.Method
+}
+
+// HttpHeader is a header sent with the request of an HTTP check. The value can be read from a file or an environment
+// variable each time the check runs, so that secrets such as API keys don't have to be stored in the configuration
+// file and rotated secrets are picked up. Value is then prepended to what was read, e.g. "Bearer ".
+type HttpHeader struct {
+	Name      string
+	Value     string
+	ValueFile string
+	ValueEnv  string
+}
+
+// Label returns the name identifying the check in logs and detailed output.
+func (httpCheck HttpCheck) Label() string {
+	return httpCheck.labelOr(httpCheck.Url)
+}
+
+// DnsCheck resolves a name, optionally asserting the answers and how long the resolution took.
+type DnsCheck struct {
+	CheckOptions
+	Host string
+	// Resolver is the address of the DNS server to query, with an optional port that defaults to 53. When empty, the
+	// system resolver is used.
+	Resolver string
+	// RecordType is one of the DnsRecordTypes. When empty, the name is resolved to its IPv4 and IPv6 addresses like any
+	// other host name.
+	RecordType string
+	// Answers must all be among the answers, and at least one answer must match AnswerRegex if it is set
+	Answers     []string
+	AnswerRegex string
+	// MaxDurationMs fails the check if the resolution took longer, in milliseconds. When 0, only the timeout applies.
+	MaxDurationMs int
+}
+
+// DnsRecordTypes are the record types a DNS check can query.
+var DnsRecordTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "PTR", "SRV", "TXT"}
+
+// Label returns the name identifying the check in logs and detailed output.
+func (dnsCheck DnsCheck) Label() string {
+	if dnsCheck.RecordType == "" {
+		return dnsCheck.labelOr(dnsCheck.Host)
+	}
+	return dnsCheck.labelOr(dnsCheck.RecordType + " " + dnsCheck.Host)
+}
+
+// TlsCheck verifies the certificate served on Address, or stored in CertFile, and how long it remains valid.
+type TlsCheck struct {
+	CheckOptions
+	// Address is the host:port to connect to, with the port defaulting to 443
+	Address string
+	// CertFile is a PEM encoded certificate (chain) on local disk, checked instead of connecting to Address
+	CertFile string
+	// ServerName is sent as SNI and verified against the certificate. Defaults to the host of Address.
+	ServerName string
+	// CAFile is a PEM encoded bundle of the CAs the chain is verified against. When empty, the system roots are used.
+	CAFile string
+	// ExpiryWarningDays reports a warning, and ExpiryCriticalDays fails the check, if the leaf certificate expires
+	// within that many days. When 0, only an expired certificate fails the check.
+	ExpiryWarningDays  int
+	ExpiryCriticalDays int
+}
+
+// Label returns the name identifying the check in logs and detailed output.
+func (tlsCheck TlsCheck) Label() string {
+	if tlsCheck.CertFile != "" {
+		return tlsCheck.labelOr(tlsCheck.CertFile)
+	}
+	return tlsCheck.labelOr(tlsCheck.Address)
+}
+
+// Probe is the payload a check sends once connected, and what it expects in return.
+type Probe struct {
+	// Send is written to the connection, and may be empty
+	Send []byte
+	// Expect is a regular expression the response must match, and ExpectBytes a byte sequence it must contain. When
+	// both are empty, no response is awaited.
+	Expect      string
+	ExpectBytes []byte
+}
+
+// AwaitsResponse returns whether the check has to wait for a response to verify it.
+func (probe Probe) AwaitsResponse() bool {
+	return probe.Expect != "" || len(probe.ExpectBytes) > 0
+}
+
+// UdpCheck sends a datagram to Address and optionally verifies the response.
+type UdpCheck struct {
+	CheckOptions
+	Probe
+	// Address is the host:port to send to. If only a port is given, the datagram is sent to 0.0.0.0.
+	Address string
+}
+
+// Label returns the name identifying the check in logs and detailed output.
+func (udpCheck UdpCheck) Label() string {
+	return udpCheck.labelOr("udp://" + udpCheck.Address)
+}
+
+// CheckLabels returns the labels of all configured checks, in the order ports, scripts, HTTP checks, DNS checks, TLS
+// checks, UDP checks.
+func (opts *Options) CheckLabels() []string {
+	var labels []string
+	for _, port := range opts.Ports {
+		labels = append(labels, port.Label())
+	}
+	for _, script := range opts.Scripts {
+		labels = append(labels, script.Label())
+	}
+	for _, httpCheck := range opts.HttpChecks {
+		labels = append(labels, httpCheck.Label())
+	}
+	for _, dnsCheck := range opts.DnsChecks {
+		labels = append(labels, dnsCheck.Label())
+	}
+	for _, tlsCheck := range opts.TlsChecks {
+		labels = append(labels, tlsCheck.Label())
+	}
+	for _, udpCheck := range opts.UdpChecks {
+		labels = append(labels, udpCheck.Label())
+	}
+	return labels
+}
+
+// ParsePorts wraps the given port strings (either a bare port or host:port) into PortChecks that use the global
+// TCP dial timeout.
+func ParsePorts(portStrings []string) []PortCheck {
+	rv := []PortCheck{}
+	for _, p := range portStrings {
+		rv = append(rv, PortCheck{Address: p})
+	}
+	return rv
 }
 
 // allowedScriptPattern enforces that scripts only contain safe alphanumeric characters,
@@ -77,7 +667,7 @@ func ParseScripts(scriptStrings []string) ([]Script, error) {
 		if len(commandArr) > 1 {
 			scriptParams = commandArr[1:]
 		}
-		rv = append(rv, Script{scriptName, scriptParams})
+		rv = append(rv, Script{Name: scriptName, Args: scriptParams})
 	}
 	return rv, nil
 }
@@ -2,6 +2,7 @@ package options
 
 import (
 	"github.com/stretchr/testify/assert"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"testing"
@@ -88,3 +89,177 @@ func TestParseScripts(t *testing.T) {
 		})
 	}
 }
+
+func TestEffectiveEndpoints(t *testing.T) {
+	opts := &Options{RunAll: true}
+	assert.Equal(t, []Endpoint{{Path: DefaultEndpointPath, RunAll: true}}, opts.EffectiveEndpoints())
+
+	opts.Endpoints = []Endpoint{{Path: "/debug", RunAll: true}}
+	assert.Equal(t, opts.Endpoints, opts.EffectiveEndpoints())
+}
+
+func TestHttpCheckEffectiveMethod(t *testing.T) {
+	assert.Equal(t, "GET", HttpCheck{Url: "http://localhost"}.EffectiveMethod())
+	assert.Equal(t, "POST", HttpCheck{Url: "http://localhost", Method: "POST"}.EffectiveMethod())
+}
+
+func TestHttpCheckEffectiveMaxRedirects(t *testing.T) {
+	assert.Equal(t, DefaultMaxRedirects, HttpCheck{}.EffectiveMaxRedirects())
+	assert.Equal(t, 3, HttpCheck{MaxRedirects: 3}.EffectiveMaxRedirects())
+	assert.Equal(t, 0, HttpCheck{MaxRedirects: 3, NoRedirects: true}.EffectiveMaxRedirects())
+}
+
+func TestParseStatusRanges(t *testing.T) {
+	testCases := []struct {
+		value         string
+		expected      StatusRanges
+		expectedError string
+	}{
+		{"200", StatusRanges{{200, 200}}, ""},
+		{"200,204,301-302,401", StatusRanges{{200, 200}, {204, 204}, {301, 302}, {401, 401}}, ""},
+		{" 200 , 300 - 399 ", StatusRanges{{200, 200}, {300, 399}}, ""},
+		{"", nil, `invalid status code or range ""`},
+		{"200,", nil, `invalid status code or range ""`},
+		{"ok", nil, `invalid status code or range "ok"`},
+		{"99", nil, `invalid status code or range "99"`},
+		{"600", nil, `invalid status code or range "600"`},
+		{"302-301", nil, `invalid status code or range "302-301"`},
+		{"200-", nil, `invalid status code or range "200-"`},
+		{"200-300-400", nil, `invalid status code or range "200-300-400"`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.value, func(t *testing.T) {
+			ranges, err := ParseStatusRanges(testCase.value)
+			if testCase.expectedError == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expected, ranges)
+			} else {
+				assert.EqualError(t, err, testCase.expectedError)
+			}
+		})
+	}
+}
+
+func TestStatusRanges(t *testing.T) {
+	ranges := StatusRanges{{200, 200}, {301, 302}}
+	assert.True(t, ranges.Contains(200))
+	assert.True(t, ranges.Contains(302))
+	assert.False(t, ranges.Contains(201))
+	assert.False(t, ranges.Contains(303))
+	assert.Equal(t, "200,301-302", ranges.String())
+}
+
+func TestSplitJsonPath(t *testing.T) {
+	testCases := []struct {
+		path          string
+		expected      []string
+		expectedError string
+	}{
+		{"status", []string{"status"}, ""},
+		{"components.db.status", []string{"components", "db", "status"}, ""},
+		{"items.0.name", []string{"items", "0", "name"}, ""},
+		{"items.#", []string{"items", "#"}, ""},
+		{`build\.version`, []string{"build.version"}, ""},
+		{`a\\b`, []string{`a\b`}, ""},
+		{"", nil, `JSON path "" has an empty key`},
+		{"a..b", nil, `JSON path "a..b" has an empty key`},
+		{"a.", nil, `JSON path "a." has an empty key`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.path, func(t *testing.T) {
+			keys, err := SplitJsonPath(testCase.path)
+			if testCase.expectedError == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expected, keys)
+			} else {
+				assert.EqualError(t, err, testCase.expectedError)
+			}
+		})
+	}
+}
+
+func TestDnsCheckLabel(t *testing.T) {
+	assert.Equal(t, "example.com", DnsCheck{Host: "example.com"}.Label())
+	assert.Equal(t, "MX example.com", DnsCheck{Host: "example.com", RecordType: "MX"}.Label())
+	assert.Equal(t, "mail", DnsCheck{CheckOptions: CheckOptions{CheckName: "mail"}, Host: "example.com", RecordType: "MX"}.Label())
+}
+
+func TestTlsCheckLabel(t *testing.T) {
+	assert.Equal(t, "example.com:443", TlsCheck{Address: "example.com:443"}.Label())
+	assert.Equal(t, "/etc/ssl/app.crt", TlsCheck{CertFile: "/etc/ssl/app.crt"}.Label())
+	assert.Equal(t, "public-cert", TlsCheck{CheckOptions: CheckOptions{CheckName: "public-cert"}, Address: "example.com:443"}.Label())
+}
+
+func TestUdpCheckLabel(t *testing.T) {
+	assert.Equal(t, "udp://localhost:514", UdpCheck{Address: "localhost:514"}.Label())
+	assert.Equal(t, "syslog", UdpCheck{CheckOptions: CheckOptions{CheckName: "syslog"}, Address: "localhost:514"}.Label())
+}
+
+func TestProbeAwaitsResponse(t *testing.T) {
+	assert.False(t, Probe{}.AwaitsResponse())
+	assert.False(t, Probe{Send: []byte("ping")}.AwaitsResponse())
+	assert.True(t, Probe{Expect: "pong"}.AwaitsResponse())
+	assert.True(t, Probe{ExpectBytes: []byte{0x00}}.AwaitsResponse())
+}
+
+func TestPortCheckEffectiveMaxBytes(t *testing.T) {
+	assert.Equal(t, DefaultProbeMaxBytes, PortCheck{Address: "25"}.EffectiveMaxBytes())
+	assert.Equal(t, 64, PortCheck{Address: "25", MaxBytes: 64}.EffectiveMaxBytes())
+}
+
+func TestEndpointIncludes(t *testing.T) {
+	assert.True(t, Endpoint{Path: "/"}.Includes("anything"), "An endpoint without checks runs every check")
+
+	endpoint := Endpoint{Path: "/livez", Checks: []string{"app", "db"}}
+	assert.True(t, endpoint.Includes("db"))
+	assert.False(t, endpoint.Includes("cache"))
+}
+
+func TestEndpointEffectiveFailureStatusCode(t *testing.T) {
+	assert.Equal(t, DefaultFailureStatusCode, Endpoint{}.EffectiveFailureStatusCode())
+	assert.Equal(t, 503, Endpoint{FailureStatusCode: 503}.EffectiveFailureStatusCode())
+}
+
+func TestEffectiveSeverity(t *testing.T) {
+	assert.Equal(t, SeverityCritical, CheckOptions{}.EffectiveSeverity())
+	assert.Equal(t, SeverityWarning, CheckOptions{Severity: SeverityWarning}.EffectiveSeverity())
+}
+
+func TestScriptEffectiveMode(t *testing.T) {
+	assert.Equal(t, ScriptModeExitCode, Script{}.EffectiveMode())
+	assert.Equal(t, ScriptModeNagios, Script{Mode: ScriptModeNagios}.EffectiveMode())
+}
+
+func TestEndpointEffectiveDegradedStatusCode(t *testing.T) {
+	assert.Equal(t, DefaultDegradedStatusCode, Endpoint{}.EffectiveDegradedStatusCode(&Options{}))
+	assert.Equal(t, 207, Endpoint{}.EffectiveDegradedStatusCode(&Options{DegradedStatus: 207}))
+	assert.Equal(t, 429, Endpoint{DegradedStatusCode: 429}.EffectiveDegradedStatusCode(&Options{DegradedStatus: 207}))
+}
+
+func TestParseCIDRs(t *testing.T) {
+	prefixes, err := ParseCIDRs([]string{"10.1.2.3/8", "192.0.2.7", "fd00::/8"})
+	assert.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.0.2.7/32"),
+		netip.MustParsePrefix("fd00::/8"),
+	}, prefixes)
+
+	_, err = ParseCIDRs([]string{"10.0.0.0/33"})
+	assert.ErrorContains(t, err, `invalid CIDR "10.0.0.0/33"`)
+}
+
+func TestAuthOptionsEnabled(t *testing.T) {
+	assert.False(t, AuthOptions{}.Enabled())
+	assert.True(t, AuthOptions{BearerTokens: []string{"token"}}.Enabled())
+	assert.True(t, AuthOptions{AllowedCIDRs: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}.Enabled())
+}
+
+func TestEndpointGatesTraffic(t *testing.T) {
+	assert.True(t, Endpoint{Path: "/"}.GatesTraffic())
+	assert.True(t, Endpoint{Role: EndpointRoleReadiness}.GatesTraffic())
+	assert.False(t, Endpoint{Role: EndpointRoleLiveness}.GatesTraffic())
+	assert.False(t, Endpoint{Role: EndpointRoleStartup}.GatesTraffic())
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// authorized reports whether the request may see the detailed status output: either no authentication is configured,
+// the request comes from an allowed source range, or it carries a valid bearer token or basic auth credential. The
+// source address is the address of the TCP peer; forwarding headers are ignored, since anybody can set them.
+func authorized(r *http.Request, auth options.AuthOptions) bool {
+	if !auth.Enabled() {
+		return true
+	}
+
+	if addrPort, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+		addr := addrPort.Addr().Unmap()
+		for _, prefix := range auth.AllowedCIDRs {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+	}
+
+	header := r.Header.Get("Authorization")
+	if token, ok := strings.CutPrefix(header, "Bearer "); ok && token != "" {
+		for _, allowed := range auth.BearerTokens {
+			if secretsEqual(token, allowed) {
+				return true
+			}
+		}
+	}
+
+	if username, password, ok := r.BasicAuth(); ok {
+		// Verify the password against a dummy hash for unknown users, so that the response time doesn't reveal which
+		// usernames exist
+		passwordBcrypt := dummyPasswordBcrypt
+		for _, credential := range auth.BasicCredentials {
+			if secretsEqual(username, credential.Username) {
+				passwordBcrypt = credential.PasswordBcrypt
+			}
+		}
+		passwordMatches := bcrypt.CompareHashAndPassword([]byte(passwordBcrypt), []byte(password)) == nil
+		if passwordMatches && passwordBcrypt != dummyPasswordBcrypt {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dummyPasswordBcrypt is the bcrypt hash that passwords of unknown users are verified against, with the default cost.
+const dummyPasswordBcrypt = "$2a$10$i/3urNKWAU4KGwSiYXTI.ut4KJvTVh3oDXwzq8GWOmkfc/9bwDuPa"
+
+// secretsEqual compares two secrets in constant time. Both are hashed first, so that the comparison doesn't reveal
+// their length either.
+func secretsEqual(a string, b string) bool {
+	hashA := sha256.Sum256([]byte(a))
+	hashB := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(hashA[:], hashB[:]) == 1
+}
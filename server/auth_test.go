@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/gruntwork-io/health-checker/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorized(t *testing.T) {
+	auth := options.AuthOptions{
+		BearerTokens: []string{"token-1", "token-2"},
+		// htpasswd -nbB ops s3cret
+		BasicCredentials: []options.BasicCredential{{Username: "ops", PasswordBcrypt: "$2y$10$IXSC3F5VesTPCWi3b8A/y.mbnF28WYDHpWkDNTSozlEmC.DWYyvOO"}},
+		AllowedCIDRs:     []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")},
+	}
+
+	testCases := []struct {
+		name       string
+		auth       options.AuthOptions
+		remoteAddr string
+		header     http.Header
+		expected   bool
+	}{
+		{"no auth configured", options.AuthOptions{}, "192.0.2.1:1234", nil, true},
+		{"anonymous", auth, "192.0.2.1:1234", nil, false},
+		{"allowed IPv4 source", auth, "10.1.2.3:1234", nil, true},
+		{"allowed IPv6 source", auth, "[fd00::1]:1234", nil, true},
+		{"IPv4-mapped IPv6 source", auth, "[::ffff:10.1.2.3]:1234", nil, true},
+		{"forwarding headers are ignored", auth, "192.0.2.1:1234", http.Header{"X-Forwarded-For": {"10.1.2.3"}}, false},
+		{"valid bearer token", auth, "192.0.2.1:1234", http.Header{"Authorization": {"Bearer token-2"}}, true},
+		{"invalid bearer token", auth, "192.0.2.1:1234", http.Header{"Authorization": {"Bearer token-3"}}, false},
+		{"empty bearer token", auth, "192.0.2.1:1234", http.Header{"Authorization": {"Bearer "}}, false},
+		{"valid basic credential", auth, "192.0.2.1:1234", http.Header{"Authorization": {basicAuthForTest("ops", "s3cret")}}, true},
+		{"wrong basic password", auth, "192.0.2.1:1234", http.Header{"Authorization": {basicAuthForTest("ops", "guess")}}, false},
+		{"wrong basic username", auth, "192.0.2.1:1234", http.Header{"Authorization": {basicAuthForTest("dev", "s3cret")}}, false},
+		{"dummy password of unknown user", auth, "192.0.2.1:1234", http.Header{"Authorization": {basicAuthForTest("dev", "dummy")}}, false},
+		{"token as basic password", auth, "192.0.2.1:1234", http.Header{"Authorization": {basicAuthForTest("ops", "token-1")}}, false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = testCase.remoteAddr
+			for key, values := range testCase.header {
+				r.Header[key] = values
+			}
+			assert.Equal(t, testCase.expected, authorized(r, testCase.auth))
+		})
+	}
+}
+
+func TestDetailedStatusRequiresAuth(t *testing.T) {
+	ports, err := test.GetFreePorts(1)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+
+	l, err := net.Listen("tcp", test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0]))
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	go handleRequests(t, l, nil)
+	defer closeListeners(t, []net.Listener{l})
+
+	opts := createOptionsForTest(t, 5, nil, nil, test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, 0), []string{test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0])})
+	opts.DetailedStatus = true
+	opts.Auth = options.AuthOptions{BearerTokens: []string{"token"}}
+	handler := endpointHandler(opts, options.Endpoint{Path: "/"}, newServerState())
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/plain", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "OK", recorder.Body.String())
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Authorization", "Bearer token")
+	recorder = httptest.NewRecorder()
+	handler(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), `"checks":[`)
+}
+
+func basicAuthForTest(username string, password string) string {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth(username, password)
+	return r.Header.Get("Authorization")
+}
@@ -0,0 +1,296 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// The states a single check can be reported in.
+const (
+	CheckStatusPassing  = "passing"
+	CheckStatusWarning  = "warning"
+	CheckStatusFailing  = "failing"
+	CheckStatusCanceled = "canceled"
+	CheckStatusPending  = "pending"
+)
+
+// CheckResult is the outcome of a single check within a runChecks pass, as reported in the detailed JSON response.
+type CheckResult struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Severity string `json:"severity"`
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+	Output   string `json:"output,omitempty"`
+	// Metrics are the numbers reported by the check along with its status, such as the performance data of a Nagios
+	// plugin
+	Metrics []Metric `json:"metrics,omitempty"`
+	// Checks are the sub-checks reported by a script in json mode
+	Checks []SubCheckResult `json:"checks,omitempty"`
+	// ConsecutiveFailures and ConsecutiveSuccesses count the runs in a row that failed or passed, across passes
+	ConsecutiveFailures  int `json:"consecutive_failures"`
+	ConsecutiveSuccesses int `json:"consecutive_successes"`
+	// Age is how long ago the result was produced, only reported when answering from background results
+	Age string `json:"age,omitempty"`
+
+	// message is the human-readable failure reported in the errors list of the detailed response
+	message string
+	// elapsed is the unformatted Duration, exported as a metric
+	elapsed time.Duration
+}
+
+// Metric is a number reported by a check along with its status.
+type Metric struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+	// Warning and Critical are the thresholds the check compared the value against, in the Nagios range format
+	Warning  string   `json:"warning,omitempty"`
+	Critical string   `json:"critical,omitempty"`
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
+}
+
+// checkOutcome is what a single run of a check reports back to runCheck.
+type checkOutcome struct {
+	output string
+	// err is set if the run found a problem. Unless warning is set too, the run failed.
+	err     error
+	warning bool
+	metrics []Metric
+	checks  []SubCheckResult
+}
+
+// check is the type-independent view of a configured TCP, script, HTTP, DNS, TLS or UDP probe, which lets runChecks
+// execute and report every kind of check the same way.
+type check struct {
+	name        string
+	kind        string
+	description string
+	timeout     time.Duration
+	interval    time.Duration
+	// failureThreshold and successThreshold are the number of consecutive failures or successes needed before the
+	// check is reported as failing or passing again
+	failureThreshold int
+	successThreshold int
+	severity         string
+	// outputInMessage appends the output of a failed run to its message, for checks whose error alone doesn't tell what
+	// went wrong
+	outputInMessage bool
+	run             func(ctx context.Context) checkOutcome
+}
+
+// endpointChecks returns the checks run by the given endpoint, in the same order as buildChecks.
+func endpointChecks(opts *options.Options, endpoint options.Endpoint) []check {
+	var checks []check
+	for _, c := range buildChecks(opts) {
+		if endpoint.Includes(c.name) {
+			checks = append(checks, c)
+		}
+	}
+	return checks
+}
+
+// buildChecks flattens the per-type check lists from the options into a single list, in the order ports, scripts,
+// HTTP checks, DNS checks, TLS checks, UDP checks.
+func buildChecks(opts *options.Options) []check {
+	var checks []check
+
+	for _, port := range opts.Ports {
+		checks = append(checks, check{
+			name:             port.Label(),
+			kind:             options.CheckTypeTcp,
+			description:      fmt.Sprintf("TCP connection to %s", port.Address),
+			timeout:          tcpTimeout(port, opts),
+			interval:         checkInterval(port.CheckOptions, opts),
+			failureThreshold: threshold(port.FailureThreshold),
+			successThreshold: threshold(port.SuccessThreshold),
+			severity:         port.EffectiveSeverity(),
+			run: func(ctx context.Context) checkOutcome {
+				return attemptTcpConnection(ctx, port, opts)
+			},
+		})
+	}
+
+	for _, script := range opts.Scripts {
+		checks = append(checks, check{
+			name:             script.Label(),
+			kind:             options.CheckTypeScript,
+			description:      fmt.Sprintf("Script %v", script.Name),
+			timeout:          scriptTimeout(script, opts),
+			interval:         checkInterval(script.CheckOptions, opts),
+			failureThreshold: threshold(script.FailureThreshold),
+			successThreshold: threshold(script.SuccessThreshold),
+			severity:         script.EffectiveSeverity(),
+			outputInMessage:  script.EffectiveMode() == options.ScriptModeExitCode,
+			run: func(ctx context.Context) checkOutcome {
+				return runScript(ctx, script, opts)
+			},
+		})
+	}
+
+	for _, httpCheck := range opts.HttpChecks {
+		checks = append(checks, check{
+			name:             httpCheck.Label(),
+			kind:             options.CheckTypeHttp,
+			description:      fmt.Sprintf("HTTP check to %s", httpCheck.Url),
+			timeout:          httpTimeout(httpCheck, opts),
+			interval:         checkInterval(httpCheck.CheckOptions, opts),
+			failureThreshold: threshold(httpCheck.FailureThreshold),
+			successThreshold: threshold(httpCheck.SuccessThreshold),
+			severity:         httpCheck.EffectiveSeverity(),
+			run: func(ctx context.Context) checkOutcome {
+				return attemptHttpConnection(ctx, httpCheck, opts)
+			},
+		})
+	}
+
+	for _, dnsCheck := range opts.DnsChecks {
+		checks = append(checks, check{
+			name:             dnsCheck.Label(),
+			kind:             options.CheckTypeDns,
+			description:      fmt.Sprintf("DNS resolution of %s", dnsCheck.Host),
+			timeout:          dnsTimeout(dnsCheck),
+			interval:         checkInterval(dnsCheck.CheckOptions, opts),
+			failureThreshold: threshold(dnsCheck.FailureThreshold),
+			successThreshold: threshold(dnsCheck.SuccessThreshold),
+			severity:         dnsCheck.EffectiveSeverity(),
+			run: func(ctx context.Context) checkOutcome {
+				return attemptDnsResolution(ctx, dnsCheck, opts)
+			},
+		})
+	}
+
+	for _, tlsCheck := range opts.TlsChecks {
+		checks = append(checks, check{
+			name:             tlsCheck.Label(),
+			kind:             options.CheckTypeTls,
+			description:      fmt.Sprintf("TLS certificate of %s", tlsCheck.Label()),
+			timeout:          tlsTimeout(tlsCheck, opts),
+			interval:         checkInterval(tlsCheck.CheckOptions, opts),
+			failureThreshold: threshold(tlsCheck.FailureThreshold),
+			successThreshold: threshold(tlsCheck.SuccessThreshold),
+			severity:         tlsCheck.EffectiveSeverity(),
+			run: func(ctx context.Context) checkOutcome {
+				return attemptTlsVerification(ctx, tlsCheck, opts)
+			},
+		})
+	}
+
+	for _, udpCheck := range opts.UdpChecks {
+		checks = append(checks, check{
+			name:             udpCheck.Label(),
+			kind:             options.CheckTypeUdp,
+			description:      fmt.Sprintf("UDP check to %s", udpCheck.Address),
+			timeout:          udpTimeout(udpCheck),
+			interval:         checkInterval(udpCheck.CheckOptions, opts),
+			failureThreshold: threshold(udpCheck.FailureThreshold),
+			successThreshold: threshold(udpCheck.SuccessThreshold),
+			severity:         udpCheck.EffectiveSeverity(),
+			run: func(ctx context.Context) checkOutcome {
+				return attemptUdpProbe(ctx, udpCheck, opts)
+			},
+		})
+	}
+
+	return checks
+}
+
+// runCheck executes a single check and converts its outcome into a CheckResult. If ctx was canceled by the time the
+// check returns, the check is reported as canceled instead of failing, since it was aborted rather than broken.
+func runCheck(ctx context.Context, c check, opts *options.Options) CheckResult {
+	logger := opts.Logger
+
+	checkStart := time.Now()
+	outcome := c.run(ctx)
+	elapsed := time.Since(checkStart)
+	output, err := outcome.output, outcome.err
+
+	result := CheckResult{
+		Name:     c.name,
+		Type:     c.kind,
+		Severity: c.severity,
+		Status:   CheckStatusPassing,
+		Duration: elapsed.String(),
+		Output:   output,
+		Metrics:  outcome.metrics,
+		Checks:   outcome.checks,
+		elapsed:  elapsed,
+	}
+
+	switch {
+	case err == nil:
+		logger.Infof("%s (%s) successful", c.description, c.name)
+	case ctx.Err() != nil:
+		// Don't report context cancelation as an explicit "failure" to avoid noise
+		result.Status = CheckStatusCanceled
+		result.Error = "canceled after another check failed"
+	case outcome.warning:
+		logger.Warnf("%s (%s) WARNING: %s", c.description, c.name, err)
+		result.Status = CheckStatusWarning
+		result.Error = err.Error()
+		result.message = fmt.Sprintf("%s reported a warning: %s", c.description, err.Error())
+	default:
+		logger.Warnf("%s (%s) FAILED: %s", c.description, c.name, err)
+		result.Status = CheckStatusFailing
+		result.Error = err.Error()
+		result.message = fmt.Sprintf("%s failed: %s", c.description, err.Error())
+		if c.outputInMessage {
+			logger.Warnf("Command output: %s", output)
+			result.message = fmt.Sprintf("%s (Output: %s)", result.message, output)
+		}
+	}
+
+	return result
+}
+
+// checkInterval returns how often the background scheduler runs a check: its own interval if it has one, otherwise the
+// global --check-interval
+func checkInterval(checkOptions options.CheckOptions, opts *options.Options) time.Duration {
+	if checkOptions.Interval > 0 {
+		return time.Duration(checkOptions.Interval) * time.Second
+	}
+	if opts.CheckInterval > 0 {
+		return time.Duration(opts.CheckInterval) * time.Second
+	}
+	return time.Second * 10
+}
+
+// threshold returns the given consecutive run threshold, defaulting to 1 so that every run counts
+func threshold(value int) int {
+	if value > 0 {
+		return value
+	}
+	return 1
+}
+
+// Run the script with its effective timeout and interpret its outcome according to its mode. In exit_code mode, the
+// output is the combined stdout and stderr, while the other modes only parse stdout.
+func runScript(ctx context.Context, script options.Script, opts *options.Options) checkOutcome {
+	timeout := scriptTimeout(script, opts)
+	opts.Logger.Infof("Executing '%v' with a timeout of %v...", script, timeout)
+
+	// Use the parent context so that if it is canceled, the script terminates immediately
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	/* #nosec G204 */
+	cmd := exec.CommandContext(ctx, script.Name, script.Args...)
+	switch script.EffectiveMode() {
+	case options.ScriptModeNagios:
+		// Plugins report on stdout, and anything on stderr would garble the status line
+		stdout, err := cmd.Output()
+		return nagiosOutcome(string(stdout), err)
+	case options.ScriptModeJson:
+		stdout, err := cmd.Output()
+		return jsonOutcome(string(stdout), err)
+	default:
+		output, err := cmd.CombinedOutput()
+		return checkOutcome{output: string(output), err: err}
+	}
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// attemptDnsResolution resolves the check's name and verifies the answers and how long the resolution took. The
+// answers are returned as the output, so that they show up in the detailed status.
+func attemptDnsResolution(ctx context.Context, dnsCheck options.DnsCheck, opts *options.Options) checkOutcome {
+	logger := opts.Logger
+	timeout := dnsTimeout(dnsCheck)
+
+	resolverName := "the system resolver"
+	if dnsCheck.Resolver != "" {
+		resolverName = dnsCheck.Resolver
+	}
+	logger.Infof("Resolving %s via %s...", dnsCheck.Label(), resolverName)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	answers, err := lookup(ctx, dnsResolver(dnsCheck.Resolver), dnsCheck.RecordType, dnsCheck.Host)
+	elapsed := time.Since(start)
+	if err != nil {
+		return checkOutcome{err: err}
+	}
+
+	outcome := checkOutcome{output: strings.Join(answers, ", ")}
+	if len(answers) == 0 {
+		outcome.err = fmt.Errorf("no %s records found for %s", recordTypeName(dnsCheck.RecordType), dnsCheck.Host)
+		return outcome
+	}
+
+	for _, expected := range dnsCheck.Answers {
+		// TXT answers are compared as is, since lookup doesn't normalize them either
+		normalized := expected
+		if dnsCheck.RecordType != "TXT" {
+			normalized = normalizeDnsAnswer(expected)
+		}
+		if !slices.Contains(answers, normalized) {
+			outcome.err = fmt.Errorf("expected answer %s not found in [%s]", expected, outcome.output)
+			return outcome
+		}
+	}
+
+	if dnsCheck.AnswerRegex != "" {
+		answerRegex, err := regexp.Compile(dnsCheck.AnswerRegex)
+		if err != nil {
+			outcome.err = fmt.Errorf("invalid answer_regex '%s': %w", dnsCheck.AnswerRegex, err)
+			return outcome
+		}
+		if !slices.ContainsFunc(answers, answerRegex.MatchString) {
+			outcome.err = fmt.Errorf("no answer in [%s] matches %s", outcome.output, dnsCheck.AnswerRegex)
+			return outcome
+		}
+	}
+
+	maxDuration := time.Duration(dnsCheck.MaxDurationMs) * time.Millisecond
+	if maxDuration > 0 && elapsed > maxDuration {
+		outcome.err = fmt.Errorf("resolution took %v, longer than the maximum of %v", elapsed.Round(time.Millisecond), maxDuration)
+	}
+	return outcome
+}
+
+// lookup resolves the given name to the answers of the given record type, normalized with normalizeDnsAnswer and
+// sorted. Without a record type, the name is resolved to its addresses.
+func lookup(ctx context.Context, resolver *net.Resolver, recordType string, host string) ([]string, error) {
+	var answers []string
+	switch recordType {
+	case "":
+		addrs, err := resolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		answers = addrs
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupNetIP(ctx, network, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.Unmap().String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		answers = []string{cname}
+	case "MX":
+		records, err := resolver.LookupMX(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			answers = append(answers, record.Host)
+		}
+	case "NS":
+		records, err := resolver.LookupNS(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			answers = append(answers, record.Host)
+		}
+	case "PTR":
+		names, err := resolver.LookupAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		answers = names
+	case "SRV":
+		_, records, err := resolver.LookupSRV(ctx, "", "", host)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			answers = append(answers, net.JoinHostPort(strings.TrimSuffix(record.Target, "."), fmt.Sprint(record.Port)))
+		}
+	case "TXT":
+		records, err := resolver.LookupTXT(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		// TXT records are case-sensitive, so they are not normalized
+		slices.Sort(records)
+		return records, nil
+	default:
+		return nil, fmt.Errorf("unsupported record type %s", recordType)
+	}
+
+	for i, answer := range answers {
+		answers[i] = normalizeDnsAnswer(answer)
+	}
+	slices.Sort(answers)
+	return answers, nil
+}
+
+// normalizeDnsAnswer makes answers comparable with the configured ones: names are lowercased without their trailing
+// dot, and IP addresses are written in their canonical form.
+func normalizeDnsAnswer(answer string) string {
+	if addr, err := netip.ParseAddr(answer); err == nil {
+		return addr.Unmap().String()
+	}
+	return strings.ToLower(strings.TrimSuffix(answer, "."))
+}
+
+// dnsResolver returns a resolver that queries the given server, or the system resolver if none is given.
+func dnsResolver(server string) *net.Resolver {
+	if server == "" {
+		return net.DefaultResolver
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+}
+
+func recordTypeName(recordType string) string {
+	if recordType == "" {
+		return "address"
+	}
+	return recordType
+}
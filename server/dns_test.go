@@ -0,0 +1,178 @@
+package server
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	dnsTypeA    = 1
+	dnsTypeTXT  = 16
+	dnsTypeAAAA = 28
+)
+
+// dnsRecordForTest is an answer served by the test DNS server, keyed by lowercase name and record type.
+type dnsRecordForTest struct {
+	name       string
+	recordType uint16
+}
+
+func TestDnsChecks(t *testing.T) {
+	resolver := startDnsServerForTest(t, map[dnsRecordForTest][][]byte{
+		{"app.internal.test.", dnsTypeA}: {
+			netip.MustParseAddr("10.0.0.2").AsSlice(),
+			netip.MustParseAddr("10.0.0.1").AsSlice(),
+		},
+		{"app.internal.test.", dnsTypeAAAA}: {netip.MustParseAddr("fd00::1").AsSlice()},
+		{"app.internal.test.", dnsTypeTXT}:  {txtForTest("v=spf1 -all"), txtForTest("v=DKIM1; k=rsa; p=MIGfMA0")},
+	}, 0)
+	slowResolver := startDnsServerForTest(t, map[dnsRecordForTest][][]byte{
+		{"app.internal.test.", dnsTypeA}: {netip.MustParseAddr("10.0.0.1").AsSlice()},
+	}, 200*time.Millisecond)
+
+	testCases := []struct {
+		name           string
+		check          options.DnsCheck
+		expectedError  string
+		expectedOutput string
+	}{
+		{"addresses", options.DnsCheck{Host: "app.internal.test", Resolver: resolver}, "", "10.0.0.1, 10.0.0.2, fd00::1"},
+		{"A records", options.DnsCheck{Host: "app.internal.test", Resolver: resolver, RecordType: "A"}, "", "10.0.0.1, 10.0.0.2"},
+		{"AAAA records", options.DnsCheck{Host: "app.internal.test", Resolver: resolver, RecordType: "AAAA", Answers: []string{"fd00:0::1"}}, "", "fd00::1"},
+		{"TXT records", options.DnsCheck{Host: "app.internal.test", Resolver: resolver, RecordType: "TXT", AnswerRegex: "^v=spf1 "}, "", "v=DKIM1; k=rsa; p=MIGfMA0, v=spf1 -all"},
+		{"expected TXT answer", options.DnsCheck{Host: "app.internal.test", Resolver: resolver, RecordType: "TXT", Answers: []string{"v=DKIM1; k=rsa; p=MIGfMA0"}}, "", "v=DKIM1; k=rsa; p=MIGfMA0, v=spf1 -all"},
+		{"TXT answers are case-sensitive", options.DnsCheck{Host: "app.internal.test", Resolver: resolver, RecordType: "TXT", Answers: []string{"v=dkim1; k=rsa; p=migfma0"}}, "expected answer v=dkim1; k=rsa; p=migfma0 not found", "v=DKIM1; k=rsa; p=MIGfMA0, v=spf1 -all"},
+		{"expected answers", options.DnsCheck{Host: "app.internal.test", Resolver: resolver, RecordType: "A", Answers: []string{"10.0.0.1", "10.0.0.2"}}, "", "10.0.0.1, 10.0.0.2"},
+		{"missing answer", options.DnsCheck{Host: "app.internal.test", Resolver: resolver, RecordType: "A", Answers: []string{"10.0.0.3"}}, "expected answer 10.0.0.3 not found in [10.0.0.1, 10.0.0.2]", "10.0.0.1, 10.0.0.2"},
+		{"regex mismatch", options.DnsCheck{Host: "app.internal.test", Resolver: resolver, RecordType: "A", AnswerRegex: `^192\.168\.`}, `no answer in [10.0.0.1, 10.0.0.2] matches ^192\.168\.`, "10.0.0.1, 10.0.0.2"},
+		{"invalid regex", options.DnsCheck{Host: "app.internal.test", Resolver: resolver, RecordType: "A", AnswerRegex: "("}, "invalid answer_regex '('", "10.0.0.1, 10.0.0.2"},
+		{"unknown name", options.DnsCheck{Host: "db.internal.test", Resolver: resolver, RecordType: "A"}, "no such host", ""},
+		{"fast enough", options.DnsCheck{Host: "app.internal.test", Resolver: slowResolver, RecordType: "A", MaxDurationMs: 5000}, "", "10.0.0.1"},
+		{"too slow", options.DnsCheck{Host: "app.internal.test", Resolver: slowResolver, RecordType: "A", MaxDurationMs: 100}, "longer than the maximum of 100ms", "10.0.0.1"},
+	}
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			outcome := attemptDnsResolution(t.Context(), testCase.check, opts)
+			if testCase.expectedError == "" {
+				assert.NoError(t, outcome.err)
+			} else {
+				assert.ErrorContains(t, outcome.err, testCase.expectedError)
+			}
+			assert.Equal(t, testCase.expectedOutput, outcome.output)
+		})
+	}
+}
+
+func TestDnsCheckResult(t *testing.T) {
+	resolver := startDnsServerForTest(t, map[dnsRecordForTest][][]byte{
+		{"app.internal.test.", dnsTypeA}: {netip.MustParseAddr("10.0.0.1").AsSlice()},
+	}, 0)
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+	opts.DnsChecks = []options.DnsCheck{{CheckOptions: options.CheckOptions{CheckName: "internal-dns"}, Host: "app.internal.test", Resolver: resolver, RecordType: "A"}}
+
+	pass := runChecks(opts, options.Endpoint{Path: "/"}, newServerState())
+	assert.Len(t, pass.results, 1)
+	assert.Equal(t, "internal-dns", pass.results[0].Name)
+	assert.Equal(t, options.CheckTypeDns, pass.results[0].Type)
+	assert.Equal(t, CheckStatusPassing, pass.results[0].Status)
+	assert.Equal(t, "10.0.0.1", pass.results[0].Output)
+}
+
+// startDnsServerForTest serves the given records over UDP until the test ends, answering every query after the given
+// delay, and returns its address. Names without any record are answered with NXDOMAIN.
+func startDnsServerForTest(t *testing.T, records map[dnsRecordForTest][][]byte, delay time.Duration) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		assert.FailNow(t, "Failed to start DNS server: %s", err.Error())
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	known := map[string]bool{}
+	for record := range records {
+		known[record.name] = true
+	}
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			response := dnsResponseForTest(buf[:n], records, known)
+			if response == nil {
+				continue
+			}
+			go func() {
+				time.Sleep(delay)
+				_, _ = conn.WriteTo(response, addr)
+			}()
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+// dnsResponseForTest builds the response to a query with a single question, or returns nil if it can't be parsed.
+func dnsResponseForTest(query []byte, records map[dnsRecordForTest][][]byte, known map[string]bool) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+
+	// Parse the question name, which starts right after the header
+	var labels []string
+	offset := 12
+	for offset < len(query) && query[offset] != 0 {
+		length := int(query[offset])
+		if offset+1+length > len(query) {
+			return nil
+		}
+		labels = append(labels, string(query[offset+1:offset+1+length]))
+		offset += 1 + length
+	}
+	questionEnd := offset + 5
+	if questionEnd > len(query) {
+		return nil
+	}
+	name := strings.ToLower(strings.Join(labels, ".")) + "."
+	recordType := binary.BigEndian.Uint16(query[offset+1:])
+	answers := records[dnsRecordForTest{name, recordType}]
+
+	// Echo the ID and question, with the response, recursion desired and recursion available flags set
+	response := append([]byte{}, query[:2]...)
+	flags := uint16(0x8180)
+	if !known[name] {
+		flags |= 3 // NXDOMAIN
+	}
+	response = binary.BigEndian.AppendUint16(response, flags)
+	response = binary.BigEndian.AppendUint16(response, 1)
+	response = binary.BigEndian.AppendUint16(response, uint16(len(answers)))
+	response = binary.BigEndian.AppendUint16(response, 0)
+	response = binary.BigEndian.AppendUint16(response, 0)
+	response = append(response, query[12:questionEnd]...)
+
+	for _, data := range answers {
+		// A pointer to the name in the question, followed by type, class IN, a TTL of 60 seconds and the data
+		response = append(response, 0xc0, 12)
+		response = binary.BigEndian.AppendUint16(response, recordType)
+		response = binary.BigEndian.AppendUint16(response, 1)
+		response = binary.BigEndian.AppendUint32(response, 60)
+		response = binary.BigEndian.AppendUint16(response, uint16(len(data)))
+		response = append(response, data...)
+	}
+	return response
+}
+
+func txtForTest(text string) []byte {
+	return append([]byte{byte(len(text))}, text...)
+}
@@ -0,0 +1,325 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// newHttpCheckRequest builds the request of the HTTP check, with its method, body and headers. Header values that are
+// read from files or environment variables are resolved every time, so that rotated secrets are picked up.
+func newHttpCheckRequest(ctx context.Context, httpCheck options.HttpCheck) (*http.Request, error) {
+	var body io.Reader
+	if httpCheck.Body != "" {
+		body = strings.NewReader(httpCheck.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, httpCheck.EffectiveMethod(), httpCheck.Url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	for _, header := range httpCheck.Headers {
+		value, err := resolveHeaderValue(header)
+		if err != nil {
+			return nil, err
+		}
+		// Go's HTTP client ignores a Host header, and only sends the Host of the request
+		if strings.EqualFold(header.Name, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Add(header.Name, value)
+	}
+	if httpCheck.UserAgent != "" {
+		req.Header.Set("User-Agent", httpCheck.UserAgent)
+	}
+	if httpCheck.Host != "" {
+		req.Host = httpCheck.Host
+	}
+	return req, nil
+}
+
+// resolveHeaderValue returns the value of the header, reading it from its file or environment variable if it has one.
+// Surrounding whitespace, such as the trailing newline of a file, is removed from what was read.
+func resolveHeaderValue(header options.HttpHeader) (string, error) {
+	switch {
+	case header.ValueFile != "":
+		data, err := os.ReadFile(header.ValueFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the value of header %s: %w", header.Name, err)
+		}
+		return header.Value + strings.TrimSpace(string(data)), nil
+	case header.ValueEnv != "":
+		value, ok := os.LookupEnv(header.ValueEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s for the value of header %s is not set", header.ValueEnv, header.Name)
+		}
+		return header.Value + strings.TrimSpace(value), nil
+	default:
+		return header.Value, nil
+	}
+}
+
+// httpCheckTlsConfig returns the TLS settings of the HTTP check's transport. The CA bundle and the client certificate
+// are read every time, so that rotated certificates are picked up.
+func httpCheckTlsConfig(httpCheck options.HttpCheck, opts *options.Options) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: httpCheck.ServerName,
+		MinVersion: httpCheck.MinTlsVersion,
+		// #nosec G402
+		InsecureSkipVerify: opts.AllowInsecureTLS,
+	}
+	if tlsConfig.ServerName == "" && httpCheck.Host != "" {
+		// The Host override is also the name of the virtual host to ask the server for a certificate of
+		tlsConfig.ServerName = httpCheck.Host
+		if host, _, err := net.SplitHostPort(httpCheck.Host); err == nil {
+			tlsConfig.ServerName = host
+		}
+	}
+
+	roots, err := loadCertPool(httpCheck.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the CA bundle: %w", err)
+	}
+	tlsConfig.RootCAs = roots
+
+	if httpCheck.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(httpCheck.ClientCertFile, httpCheck.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(httpCheck.PinnedFingerprints) > 0 {
+		// VerifyConnection also runs when verification is skipped, so pins hold even with --allow-insecure-tls
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			certs := slices.Clone(state.PeerCertificates)
+			// The verified chains also hold the root CA, which servers usually don't present
+			for _, chain := range state.VerifiedChains {
+				certs = append(certs, chain...)
+			}
+			return verifyPinnedFingerprints(httpCheck.PinnedFingerprints, certs)
+		}
+	}
+	return tlsConfig, nil
+}
+
+// verifyPinnedFingerprints returns an error unless the SHA-256 fingerprint of one of the certificates is pinned. The
+// error names the first certificate, which is the server's leaf.
+func verifyPinnedFingerprints(pins [][]byte, certs []*x509.Certificate) error {
+	for _, cert := range certs {
+		fingerprint := sha256.Sum256(cert.Raw)
+		for _, pin := range pins {
+			if bytes.Equal(fingerprint[:], pin) {
+				return nil
+			}
+		}
+	}
+	if len(certs) == 0 {
+		return errors.New("server presented no certificate to match the pinned fingerprints")
+	}
+	leaf := sha256.Sum256(certs[0].Raw)
+	return fmt.Errorf("no certificate presented by the server matches a pinned fingerprint, %s has fingerprint %X", certs[0].Subject, leaf)
+}
+
+// httpCheckRedirectPolicy returns the CheckRedirect function of the HTTP check's client, which follows at most
+// EffectiveMaxRedirects redirects. When the check doesn't follow redirects, the redirect response itself is verified.
+func httpCheckRedirectPolicy(httpCheck options.HttpCheck) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if httpCheck.NoRedirects {
+			return http.ErrUseLastResponse
+		}
+		if maxRedirects := httpCheck.EffectiveMaxRedirects(); len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return nil
+	}
+}
+
+// verifyHttpStatus returns an error if the status code of the response isn't one the check expects, which is any 2xx
+// status unless the check lists the expected ones.
+func verifyHttpStatus(httpCheck options.HttpCheck, statusCode int) error {
+	if len(httpCheck.ExpectedStatus) == 0 {
+		if statusCode < 200 || statusCode >= 300 {
+			return fmt.Errorf("HTTP check returned non-2xx status code: %d", statusCode)
+		}
+		return nil
+	}
+	if !httpCheck.ExpectedStatus.Contains(statusCode) {
+		return fmt.Errorf("HTTP check returned status code %d, expected %s", statusCode, httpCheck.ExpectedStatus)
+	}
+	return nil
+}
+
+// verifyFinalUrl returns an error if the URL of the final response, after following redirects, doesn't match the
+// check's final_url_regex.
+func verifyFinalUrl(httpCheck options.HttpCheck, resp *http.Response) error {
+	if httpCheck.FinalUrlRegex == "" {
+		return nil
+	}
+	finalUrlRegex, err := regexp.Compile(httpCheck.FinalUrlRegex)
+	if err != nil {
+		return fmt.Errorf("invalid final_url_regex '%s': %w", httpCheck.FinalUrlRegex, err)
+	}
+	finalUrl := resp.Request.URL.String()
+	if !finalUrlRegex.MatchString(finalUrl) {
+		return fmt.Errorf("HTTP check ended at %s, which doesn't match final_url_regex '%s'", finalUrl, httpCheck.FinalUrlRegex)
+	}
+	return nil
+}
+
+// verifyHeaderAssertions returns an error describing the first assertion that doesn't hold for the response headers.
+func verifyHeaderAssertions(assertions []options.HeaderAssertion, header http.Header) error {
+	for _, assertion := range assertions {
+		values := header.Values(assertion.Name)
+		joined := strings.Join(values, ", ")
+
+		if assertion.Exists != nil && !*assertion.Exists {
+			if len(values) > 0 {
+				return fmt.Errorf("response header %s is %q, expected it not to be present", assertion.Name, joined)
+			}
+			continue
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("response header %s not found", assertion.Name)
+		}
+		if assertion.Equals != nil && !slices.Contains(values, *assertion.Equals) {
+			return fmt.Errorf("response header %s is %q, expected %s", assertion.Name, joined, *assertion.Equals)
+		}
+		if assertion.Regex != "" {
+			regex, err := regexp.Compile(assertion.Regex)
+			if err != nil {
+				return fmt.Errorf("invalid regex '%s' for response header %s: %w", assertion.Regex, assertion.Name, err)
+			}
+			if !slices.ContainsFunc(values, regex.MatchString) {
+				return fmt.Errorf("response header %s is %q, which doesn't match %s", assertion.Name, joined, assertion.Regex)
+			}
+		}
+	}
+	return nil
+}
+
+// exceedsLatencyBudget reports whether the duration of a request is longer than the given budget in milliseconds. A
+// budget of 0 is disabled.
+func exceedsLatencyBudget(elapsed time.Duration, budgetMs int) bool {
+	return budgetMs > 0 && elapsed > time.Duration(budgetMs)*time.Millisecond
+}
+
+// httpTimings records the phases of an HTTP check's request with httptrace. When redirects are followed, the phases of
+// the final request are kept.
+type httpTimings struct {
+	mutex  sync.Mutex
+	phases httpPhases
+}
+
+// httpPhases are the times at which the phases of a request started and ended. Phases that didn't happen, such as DNS
+// resolution of an IP address, TLS for plain HTTP, or connecting when a connection was reused, stay zero.
+type httpPhases struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+}
+
+// clientTrace returns the hooks that record the phases. They may be called concurrently, e.g. when dialing several
+// addresses of a host at once.
+func (timings *httpTimings) clientTrace() *httptrace.ClientTrace {
+	record := func(update func(phases *httpPhases, now time.Time)) {
+		now := time.Now()
+		timings.mutex.Lock()
+		defer timings.mutex.Unlock()
+		update(&timings.phases, now)
+	}
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			record(func(phases *httpPhases, now time.Time) { *phases = httpPhases{start: now} })
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			record(func(phases *httpPhases, now time.Time) { phases.dnsStart = now })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			record(func(phases *httpPhases, now time.Time) { phases.dnsDone = now })
+		},
+		ConnectStart: func(string, string) {
+			record(func(phases *httpPhases, now time.Time) {
+				if phases.connectStart.IsZero() {
+					phases.connectStart = now
+				}
+			})
+		},
+		ConnectDone: func(_ string, _ string, err error) {
+			record(func(phases *httpPhases, now time.Time) {
+				if err == nil && phases.connectDone.IsZero() {
+					phases.connectDone = now
+				}
+			})
+		},
+		TLSHandshakeStart: func() {
+			record(func(phases *httpPhases, now time.Time) { phases.tlsStart = now })
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			record(func(phases *httpPhases, now time.Time) {
+				if err == nil {
+					phases.tlsDone = now
+				}
+			})
+		},
+		GotFirstResponseByte: func() {
+			record(func(phases *httpPhases, now time.Time) { phases.firstByte = now })
+		},
+	}
+}
+
+// metrics returns the durations of the phases that happened, and the total duration of the check's request, in
+// seconds. The total duration carries the latency budgets of the check as its thresholds.
+func (timings *httpTimings) metrics(httpCheck options.HttpCheck, elapsed time.Duration) []Metric {
+	timings.mutex.Lock()
+	phases := timings.phases
+	timings.mutex.Unlock()
+
+	var metrics []Metric
+	addPhase := func(name string, start time.Time, end time.Time) {
+		if !start.IsZero() && !end.IsZero() {
+			metrics = append(metrics, Metric{Name: name, Value: end.Sub(start).Seconds(), Unit: "s"})
+		}
+	}
+	addPhase("dns", phases.dnsStart, phases.dnsDone)
+	addPhase("connect", phases.connectStart, phases.connectDone)
+	addPhase("tls", phases.tlsStart, phases.tlsDone)
+	addPhase("ttfb", phases.start, phases.firstByte)
+
+	total := Metric{Name: "total", Value: elapsed.Seconds(), Unit: "s"}
+	if httpCheck.LatencyWarningMs > 0 {
+		total.Warning = formatSeconds(httpCheck.LatencyWarningMs)
+	}
+	if httpCheck.LatencyCriticalMs > 0 {
+		total.Critical = formatSeconds(httpCheck.LatencyCriticalMs)
+	}
+	return append(metrics, total)
+}
+
+func formatSeconds(ms int) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'f', -1, 64)
+}
@@ -0,0 +1,362 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpCheckRequest(t *testing.T) {
+	tmpDir := t.TempDir()
+	tokenFile := filepath.Join(tmpDir, "token")
+	assert.NoError(t, os.WriteFile(tokenFile, []byte("s3cr3t\n"), 0600))
+	t.Setenv("HEALTH_CHECKER_TEST_API_KEY", "k3y")
+
+	var received *http.Request
+	var receivedBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received, receivedBody = r, string(body)
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name          string
+		check         options.HttpCheck
+		expectedError string
+		verify        func(t *testing.T)
+	}{
+		{
+			"defaults",
+			options.HttpCheck{Url: server.URL + "/health"},
+			"",
+			func(t *testing.T) {
+				assert.Equal(t, http.MethodGet, received.Method)
+				assert.Equal(t, "/health", received.URL.Path)
+				assert.Equal(t, "Go-http-client/1.1", received.UserAgent())
+				assert.Equal(t, "", receivedBody)
+			},
+		},
+		{
+			"method and body",
+			options.HttpCheck{Url: server.URL, Method: http.MethodPost, Body: `{"deep": true}`, Headers: []options.HttpHeader{{Name: "Content-Type", Value: "application/json"}}},
+			"",
+			func(t *testing.T) {
+				assert.Equal(t, http.MethodPost, received.Method)
+				assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+				assert.Equal(t, `{"deep": true}`, receivedBody)
+			},
+		},
+		{
+			"headers from values, files and env",
+			options.HttpCheck{Url: server.URL, Headers: []options.HttpHeader{
+				{Name: "Authorization", Value: "Bearer ", ValueFile: tokenFile},
+				{Name: "X-Api-Key", ValueEnv: "HEALTH_CHECKER_TEST_API_KEY"},
+				{Name: "X-Tag", Value: "a"},
+				{Name: "X-Tag", Value: "b"},
+			}},
+			"",
+			func(t *testing.T) {
+				assert.Equal(t, "Bearer s3cr3t", received.Header.Get("Authorization"))
+				assert.Equal(t, "k3y", received.Header.Get("X-Api-Key"))
+				assert.Equal(t, []string{"a", "b"}, received.Header.Values("X-Tag"))
+			},
+		},
+		{
+			"host and user agent",
+			options.HttpCheck{Url: server.URL, Host: "api.internal", UserAgent: "health-checker"},
+			"",
+			func(t *testing.T) {
+				assert.Equal(t, "api.internal", received.Host)
+				assert.Equal(t, "health-checker", received.UserAgent())
+			},
+		},
+		{
+			"host header",
+			options.HttpCheck{Url: server.URL, Headers: []options.HttpHeader{{Name: "host", Value: "api.internal"}}},
+			"",
+			func(t *testing.T) {
+				assert.Equal(t, "api.internal", received.Host)
+			},
+		},
+		{
+			"missing header file",
+			options.HttpCheck{Url: server.URL, Headers: []options.HttpHeader{{Name: "Authorization", ValueFile: filepath.Join(tmpDir, "missing")}}},
+			"failed to read the value of header Authorization",
+			nil,
+		},
+		{
+			"missing header env",
+			options.HttpCheck{Url: server.URL, Headers: []options.HttpHeader{{Name: "X-Api-Key", ValueEnv: "HEALTH_CHECKER_TEST_UNSET"}}},
+			"environment variable HEALTH_CHECKER_TEST_UNSET for the value of header X-Api-Key is not set",
+			nil,
+		},
+	}
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			received = nil
+			err := attemptHttpConnection(t.Context(), testCase.check, opts).err
+			if testCase.expectedError != "" {
+				assert.ErrorContains(t, err, testCase.expectedError)
+				assert.Nil(t, received)
+				return
+			}
+			assert.NoError(t, err)
+			if assert.NotNil(t, received) {
+				testCase.verify(t)
+			}
+		})
+	}
+}
+
+func TestHttpCheckHostOverridesServerName(t *testing.T) {
+	var serverName string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverName = r.TLS.ServerName
+	}))
+	defer server.Close()
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+	opts.AllowInsecureTLS = true
+
+	err := attemptHttpConnection(t.Context(), options.HttpCheck{Url: server.URL, Host: "api.internal:8443"}, opts).err
+	assert.NoError(t, err)
+	assert.Equal(t, "api.internal", serverName)
+}
+
+func TestHttpCheckTlsSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	ca, caKey := createCertificateForTest(t, "test-ca", nil, nil)
+	caFile := filepath.Join(tmpDir, "ca.crt")
+	serverCertFile, serverKeyFile := filepath.Join(tmpDir, "server.crt"), filepath.Join(tmpDir, "server.key")
+	clientCertFile, clientKeyFile := filepath.Join(tmpDir, "client.crt"), filepath.Join(tmpDir, "client.key")
+	writePem(t, caFile, "CERTIFICATE", ca.Raw)
+	writeCertificateForTest(t, "server", ca, caKey, serverCertFile, serverKeyFile)
+	writeCertificateForTest(t, "client", ca, caKey, clientCertFile, clientKeyFile)
+	serverCert, err := tls.LoadX509KeyPair(serverCertFile, serverKeyFile)
+	assert.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+
+	startServer := func(tlsConfig *tls.Config) string {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.TLS = tlsConfig
+		server.StartTLS()
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+	// The server certificate is only valid for localhost, while httptest servers listen on 127.0.0.1
+	tls12Url := startServer(&tls.Config{Certificates: []tls.Certificate{serverCert}, MaxVersion: tls.VersionTLS12})
+	mtlsUrl := startServer(&tls.Config{Certificates: []tls.Certificate{serverCert}, ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs})
+
+	leafFingerprint := sha256.Sum256(serverCert.Leaf.Raw)
+	caFingerprint := sha256.Sum256(ca.Raw)
+	otherFingerprint := sha256.Sum256([]byte("other"))
+
+	testCases := []struct {
+		name          string
+		check         options.HttpCheck
+		insecure      bool
+		expectedError string
+	}{
+		{"CA bundle", options.HttpCheck{Url: tls12Url, ServerName: "localhost", CAFile: caFile}, false, ""},
+		{"system roots", options.HttpCheck{Url: tls12Url, ServerName: "localhost"}, false, "certificate signed by unknown authority"},
+		{"wrong server name", options.HttpCheck{Url: tls12Url, ServerName: "example.com", CAFile: caFile}, false, "not example.com"},
+		{"server name overrides host", options.HttpCheck{Url: tls12Url, Host: "example.com", ServerName: "localhost", CAFile: caFile}, false, ""},
+		{"missing CA bundle", options.HttpCheck{Url: tls12Url, CAFile: filepath.Join(tmpDir, "missing.crt")}, false, "failed to load the CA bundle"},
+		{"minimum TLS version", options.HttpCheck{Url: tls12Url, ServerName: "localhost", CAFile: caFile, MinTlsVersion: tls.VersionTLS13}, false, "protocol version"},
+		{"client certificate", options.HttpCheck{Url: mtlsUrl, ServerName: "localhost", CAFile: caFile, ClientCertFile: clientCertFile, ClientKeyFile: clientKeyFile}, false, ""},
+		{"no client certificate", options.HttpCheck{Url: mtlsUrl, ServerName: "localhost", CAFile: caFile}, false, "HTTP request failed"},
+		{"missing client certificate", options.HttpCheck{Url: mtlsUrl, ClientCertFile: filepath.Join(tmpDir, "missing.crt"), ClientKeyFile: clientKeyFile}, false, "failed to load the client certificate"},
+		{"pinned leaf", options.HttpCheck{Url: tls12Url, ServerName: "localhost", CAFile: caFile, PinnedFingerprints: [][]byte{otherFingerprint[:], leafFingerprint[:]}}, false, ""},
+		{"pinned CA", options.HttpCheck{Url: tls12Url, ServerName: "localhost", CAFile: caFile, PinnedFingerprints: [][]byte{caFingerprint[:]}}, false, ""},
+		{"pin mismatch", options.HttpCheck{Url: tls12Url, ServerName: "localhost", CAFile: caFile, PinnedFingerprints: [][]byte{otherFingerprint[:]}}, false, "no certificate presented by the server matches a pinned fingerprint, CN=server has fingerprint"},
+		{"pinned without verification", options.HttpCheck{Url: tls12Url, PinnedFingerprints: [][]byte{leafFingerprint[:]}}, true, ""},
+		{"pin mismatch without verification", options.HttpCheck{Url: tls12Url, PinnedFingerprints: [][]byte{otherFingerprint[:]}}, true, "matches a pinned fingerprint"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+			opts.AllowInsecureTLS = testCase.insecure
+			err := attemptHttpConnection(t.Context(), testCase.check, opts).err
+			if testCase.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, testCase.expectedError)
+			}
+		})
+	}
+}
+
+func TestHttpCheckStatusAndRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/unauthorized", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusFound)
+	})
+	// /hops/N redirects N times before answering
+	mux.HandleFunc("/hops/", func(w http.ResponseWriter, r *http.Request) {
+		hops, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hops/"))
+		if hops > 0 {
+			http.Redirect(w, r, "/hops/"+strconv.Itoa(hops-1), http.StatusFound)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	testCases := []struct {
+		name          string
+		check         options.HttpCheck
+		expectedError string
+	}{
+		{"2xx by default", options.HttpCheck{Url: server.URL + "/ok"}, ""},
+		{"non-2xx by default", options.HttpCheck{Url: server.URL + "/unauthorized"}, "HTTP check returned non-2xx status code: 401"},
+		{"expected status", options.HttpCheck{Url: server.URL + "/unauthorized", ExpectedStatus: options.StatusRanges{{Min: 200, Max: 200}, {Min: 401, Max: 401}}}, ""},
+		{"unexpected status", options.HttpCheck{Url: server.URL + "/ok", ExpectedStatus: options.StatusRanges{{Min: 401, Max: 403}}}, "HTTP check returned status code 200, expected 401-403"},
+		{"redirect followed", options.HttpCheck{Url: server.URL + "/login"}, ""},
+		{"redirect not followed", options.HttpCheck{Url: server.URL + "/login", NoRedirects: true}, "HTTP check returned non-2xx status code: 302"},
+		{"redirect expected", options.HttpCheck{Url: server.URL + "/login", NoRedirects: true, ExpectedStatus: options.StatusRanges{{Min: 301, Max: 302}}}, ""},
+		{"within max redirects", options.HttpCheck{Url: server.URL + "/hops/2", MaxRedirects: 3}, ""},
+		{"exactly max redirects", options.HttpCheck{Url: server.URL + "/hops/3", MaxRedirects: 3}, ""},
+		{"too many redirects", options.HttpCheck{Url: server.URL + "/hops/3", MaxRedirects: 2}, "stopped after 2 redirects"},
+		{"exactly default max redirects", options.HttpCheck{Url: server.URL + "/hops/10"}, ""},
+		{"too many default redirects", options.HttpCheck{Url: server.URL + "/hops/11"}, "stopped after 10 redirects"},
+		{"final URL", options.HttpCheck{Url: server.URL + "/login", FinalUrlRegex: "/ok$"}, ""},
+		{"unexpected final URL", options.HttpCheck{Url: server.URL + "/hops/1", FinalUrlRegex: "/ok$"}, "HTTP check ended at " + server.URL + "/hops/0, which doesn't match final_url_regex '/ok$'"},
+		{"invalid final URL regex", options.HttpCheck{Url: server.URL + "/ok", FinalUrlRegex: "("}, "invalid final_url_regex '('"},
+	}
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := attemptHttpConnection(t.Context(), testCase.check, opts).err
+			if testCase.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, testCase.expectedError)
+			}
+		})
+	}
+}
+
+func TestHttpCheckHeaderAssertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("X-Version", "1.4.2")
+		w.Header().Add("Cache-Control", "no-cache")
+		w.Header().Add("Cache-Control", "no-store")
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name          string
+		assertion     options.HeaderAssertion
+		expectedError string
+	}{
+		{"present", options.HeaderAssertion{Name: "X-Version", Exists: ptr(true)}, ""},
+		{"case-insensitive name", options.HeaderAssertion{Name: "x-version", Equals: ptr("1.4.2")}, ""},
+		{"missing", options.HeaderAssertion{Name: "X-Request-Id", Exists: ptr(true)}, "response header X-Request-Id not found"},
+		{"absent", options.HeaderAssertion{Name: "Server", Exists: ptr(false)}, ""},
+		{"not absent", options.HeaderAssertion{Name: "X-Version", Exists: ptr(false)}, `response header X-Version is "1.4.2", expected it not to be present`},
+		{"equals one of the values", options.HeaderAssertion{Name: "Cache-Control", Equals: ptr("no-store")}, ""},
+		{"not equals", options.HeaderAssertion{Name: "Cache-Control", Equals: ptr("private")}, `response header Cache-Control is "no-cache, no-store", expected private`},
+		{"regex", options.HeaderAssertion{Name: "Content-Type", Regex: "^application/json"}, ""},
+		{"regex mismatch", options.HeaderAssertion{Name: "Content-Type", Regex: "^text/"}, `response header Content-Type is "application/json; charset=utf-8", which doesn't match ^text/`},
+		{"invalid regex", options.HeaderAssertion{Name: "Content-Type", Regex: "("}, "invalid regex '(' for response header Content-Type: error parsing regexp: missing closing ): `(`"},
+		{"comparison on a missing header", options.HeaderAssertion{Name: "X-Request-Id", Regex: "."}, "response header X-Request-Id not found"},
+	}
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			check := options.HttpCheck{Url: server.URL, HeaderAssertions: []options.HeaderAssertion{testCase.assertion}}
+			err := attemptHttpConnection(t.Context(), check, opts).err
+			if testCase.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, testCase.expectedError)
+			}
+		})
+	}
+}
+
+func TestHttpCheckLatencyBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(150 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name            string
+		check           options.HttpCheck
+		expectedError   string
+		expectedWarning bool
+	}{
+		{"no budget", options.HttpCheck{Url: server.URL}, "", false},
+		{"within budget", options.HttpCheck{Url: server.URL, LatencyWarningMs: 4000, LatencyCriticalMs: 5000}, "", false},
+		{"warning budget exceeded", options.HttpCheck{Url: server.URL, LatencyWarningMs: 50, LatencyCriticalMs: 5000}, "longer than the warning latency budget of 50ms", true},
+		{"critical budget exceeded", options.HttpCheck{Url: server.URL, LatencyWarningMs: 50, LatencyCriticalMs: 100}, "longer than the critical latency budget of 100ms", false},
+		{"failure takes precedence", options.HttpCheck{Url: server.URL + "/", LatencyWarningMs: 50, ExpectedStatus: options.StatusRanges{{Min: 204, Max: 204}}}, "HTTP check returned status code 200, expected 204", false},
+	}
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			outcome := attemptHttpConnection(t.Context(), testCase.check, opts)
+			if testCase.expectedError == "" {
+				assert.NoError(t, outcome.err)
+			} else {
+				assert.ErrorContains(t, outcome.err, testCase.expectedError)
+			}
+			assert.Equal(t, testCase.expectedWarning, outcome.warning)
+		})
+	}
+}
+
+func TestHttpCheckTimings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+	opts.AllowInsecureTLS = true
+	opts.HttpChecks = []options.HttpCheck{{CheckOptions: options.CheckOptions{CheckName: "api"}, Url: strings.Replace(server.URL, "127.0.0.1", "localhost", 1), LatencyWarningMs: 10, LatencyCriticalMs: 2500}}
+
+	pass := runChecks(opts, options.Endpoint{Path: "/"}, newServerState())
+	assert.Len(t, pass.results, 1)
+	result := pass.results[0]
+	assert.Equal(t, CheckStatusWarning, result.Status)
+
+	metrics := map[string]Metric{}
+	var names []string
+	for _, metric := range result.Metrics {
+		metrics[metric.Name] = metric
+		names = append(names, metric.Name)
+		assert.Equal(t, "s", metric.Unit)
+		assert.Greater(t, metric.Value, 0.0, metric.Name)
+	}
+	assert.Equal(t, []string{"dns", "connect", "tls", "ttfb", "total"}, names)
+	assert.GreaterOrEqual(t, metrics["ttfb"].Value, 0.05)
+	assert.GreaterOrEqual(t, metrics["total"].Value, metrics["ttfb"].Value)
+	assert.Equal(t, "0.01", metrics["total"].Warning)
+	assert.Equal(t, "2.5", metrics["total"].Critical)
+}
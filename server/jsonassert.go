@@ -0,0 +1,146 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// verifyJsonAssertions parses the response body as a single JSON document and returns an error describing the first
+// assertion that doesn't hold.
+func verifyJsonAssertions(assertions []options.JsonAssertion, body []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// Keep numbers as they were written, so that large integers can be compared exactly
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return fmt.Errorf("HTTP response body is not valid JSON: %w", err)
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("HTTP response body is not valid JSON: unexpected data after the document")
+	}
+
+	for _, assertion := range assertions {
+		if err := verifyJsonAssertion(assertion, document); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyJsonAssertion returns an error if the assertion doesn't hold for the document.
+func verifyJsonAssertion(assertion options.JsonAssertion, document any) error {
+	keys, err := options.SplitJsonPath(assertion.Path)
+	if err != nil {
+		return err
+	}
+	value, found := lookupJsonPath(document, keys)
+
+	if assertion.Exists != nil && !*assertion.Exists {
+		if found {
+			return fmt.Errorf("JSON path %s is %s, expected it not to exist", assertion.Path, jsonText(value))
+		}
+		return nil
+	}
+	if !found {
+		return fmt.Errorf("JSON path %s not found", assertion.Path)
+	}
+
+	text := jsonValueString(value)
+	if assertion.Equals != nil && !jsonValueEquals(value, *assertion.Equals) {
+		return fmt.Errorf("JSON path %s is %s, expected %s", assertion.Path, jsonText(value), *assertion.Equals)
+	}
+	if assertion.Regex != "" {
+		regex, err := regexp.Compile(assertion.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex '%s' for JSON path %s: %w", assertion.Regex, assertion.Path, err)
+		}
+		if !regex.MatchString(text) {
+			return fmt.Errorf("JSON path %s is %s, which doesn't match %s", assertion.Path, jsonText(value), assertion.Regex)
+		}
+	}
+	if assertion.Min != nil || assertion.Max != nil {
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("JSON path %s is %s, expected a number", assertion.Path, jsonText(value))
+		}
+		// Every JSON number can be parsed as a float
+		n, _ := number.Float64()
+		if assertion.Min != nil && n < *assertion.Min {
+			return fmt.Errorf("JSON path %s is %s, below the minimum of %v", assertion.Path, number, *assertion.Min)
+		}
+		if assertion.Max != nil && n > *assertion.Max {
+			return fmt.Errorf("JSON path %s is %s, above the maximum of %v", assertion.Path, number, *assertion.Max)
+		}
+	}
+	return nil
+}
+
+// lookupJsonPath returns the value at the path of keys in a document decoded with UseNumber. Arrays are indexed by
+// number, and # is the length of an array.
+func lookupJsonPath(document any, keys []string) (any, bool) {
+	value := document
+	for _, key := range keys {
+		switch node := value.(type) {
+		case map[string]any:
+			child, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			value = child
+		case []any:
+			if key == "#" {
+				value = json.Number(strconv.Itoa(len(node)))
+				continue
+			}
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			value = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// jsonValueEquals compares a JSON value with the expected text. Numbers are compared numerically with arbitrary
+// precision, so that 1.0 equals 1 but 9007199254740993 doesn't equal 9007199254740992.
+func jsonValueEquals(value any, expected string) bool {
+	if jsonValueString(value) == expected {
+		return true
+	}
+	if number, ok := value.(json.Number); ok {
+		actual, ok1 := new(big.Float).SetPrec(jsonNumberPrecision).SetString(number.String())
+		wanted, ok2 := new(big.Float).SetPrec(jsonNumberPrecision).SetString(expected)
+		return ok1 && ok2 && actual.Cmp(wanted) == 0
+	}
+	return false
+}
+
+// jsonNumberPrecision is the precision, in bits, with which numbers are compared. Integers of up to 154 digits are
+// compared exactly, and decimals are rounded far beyond the 53 bits of a float64.
+const jsonNumberPrecision = 512
+
+// jsonValueString returns a JSON value as text: strings as they are, and anything else as compact JSON.
+func jsonValueString(value any) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+	return jsonText(value)
+}
+
+// jsonText returns a JSON value as compact JSON, for error messages.
+func jsonText(value any) string {
+	// Values decoded from JSON can always be encoded again
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+const actuatorHealthForTest = `{
+	"status": "UP",
+	"components": {
+		"db": {"status": "UP", "details": {"database": "PostgreSQL", "validationQuery": "isValid()"}},
+		"diskSpace": {"status": "UP", "details": {"total": 499963174912, "free": 91300659200, "threshold": 10485760}},
+		"ping": {"status": "UP"}
+	},
+	"groups": ["liveness", "readiness"],
+	"build.version": "1.4.2",
+	"ratio": 0.5,
+	"sequence": 9007199254740993,
+	"healthy": true,
+	"error": null
+}`
+
+func TestJsonAssertions(t *testing.T) {
+	testCases := []struct {
+		name          string
+		assertion     options.JsonAssertion
+		expectedError string
+	}{
+		{"equals", options.JsonAssertion{Path: "status", Equals: ptr("UP")}, ""},
+		{"not equals", options.JsonAssertion{Path: "components.db.status", Equals: ptr("DOWN")}, `JSON path components.db.status is "UP", expected DOWN`},
+		{"equals number", options.JsonAssertion{Path: "components.diskSpace.details.threshold", Equals: ptr("1.048576e7")}, ""},
+		{"equals decimal number", options.JsonAssertion{Path: "ratio", Equals: ptr("0.50")}, ""},
+		{"equals large integer", options.JsonAssertion{Path: "sequence", Equals: ptr("9007199254740993")}, ""},
+		{"large integer compared exactly", options.JsonAssertion{Path: "sequence", Equals: ptr("9007199254740992")}, "JSON path sequence is 9007199254740993, expected 9007199254740992"},
+		{"equals bool", options.JsonAssertion{Path: "healthy", Equals: ptr("true")}, ""},
+		{"equals null", options.JsonAssertion{Path: "error", Equals: ptr("null")}, ""},
+		{"equals array", options.JsonAssertion{Path: "groups", Equals: ptr(`["liveness","readiness"]`)}, ""},
+		{"array index", options.JsonAssertion{Path: "groups.1", Equals: ptr("readiness")}, ""},
+		{"array length", options.JsonAssertion{Path: "groups.#", Equals: ptr("2")}, ""},
+		{"escaped dot", options.JsonAssertion{Path: `build\.version`, Regex: `^1\.`}, ""},
+		{"regex", options.JsonAssertion{Path: "components.db.details.database", Regex: "^(PostgreSQL|MySQL)$"}, ""},
+		{"regex mismatch", options.JsonAssertion{Path: "components.ping.status", Regex: "^DOWN$"}, `JSON path components.ping.status is "UP", which doesn't match ^DOWN$`},
+		{"within bounds", options.JsonAssertion{Path: "components.diskSpace.details.free", Min: ptr(1073741824.0), Max: ptr(499963174912.0)}, ""},
+		{"below minimum", options.JsonAssertion{Path: "ratio", Min: ptr(0.75)}, "JSON path ratio is 0.5, below the minimum of 0.75"},
+		{"above maximum", options.JsonAssertion{Path: "groups.#", Max: ptr(1.0)}, "JSON path groups.# is 2, above the maximum of 1"},
+		{"not a number", options.JsonAssertion{Path: "status", Min: ptr(1.0)}, `JSON path status is "UP", expected a number`},
+		{"exists", options.JsonAssertion{Path: "components.ping", Exists: ptr(true)}, ""},
+		{"missing", options.JsonAssertion{Path: "components.redis", Exists: ptr(true)}, "JSON path components.redis not found"},
+		{"missing with comparison", options.JsonAssertion{Path: "components.redis.status", Equals: ptr("UP")}, "JSON path components.redis.status not found"},
+		{"index out of range", options.JsonAssertion{Path: "groups.2", Exists: ptr(true)}, "JSON path groups.2 not found"},
+		{"key of a string", options.JsonAssertion{Path: "status.value", Exists: ptr(true)}, "JSON path status.value not found"},
+		{"absent", options.JsonAssertion{Path: "components.redis", Exists: ptr(false)}, ""},
+		{"invalid path", options.JsonAssertion{Path: "components..status", Exists: ptr(true)}, `JSON path "components..status" has an empty key`},
+		{"invalid regex", options.JsonAssertion{Path: "status", Regex: "("}, "invalid regex '(' for JSON path status: error parsing regexp: missing closing ): `(`"},
+		{"not absent", options.JsonAssertion{Path: "components.ping", Exists: ptr(false)}, `JSON path components.ping is {"status":"UP"}, expected it not to exist`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := verifyJsonAssertions([]options.JsonAssertion{testCase.assertion}, []byte(actuatorHealthForTest))
+			if testCase.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, testCase.expectedError)
+			}
+		})
+	}
+}
+
+func TestJsonAssertionsInvalidBody(t *testing.T) {
+	err := verifyJsonAssertions([]options.JsonAssertion{{Path: "status", Exists: ptr(true)}}, []byte("<html>UP</html>"))
+	assert.ErrorContains(t, err, "HTTP response body is not valid JSON")
+
+	err = verifyJsonAssertions([]options.JsonAssertion{{Path: "ok", Exists: ptr(true)}}, []byte(`{"ok":true} garbage`))
+	assert.EqualError(t, err, "HTTP response body is not valid JSON: unexpected data after the document")
+
+	// Surrounding whitespace, such as a trailing newline, is fine
+	err = verifyJsonAssertions([]options.JsonAssertion{{Path: "ok", Exists: ptr(true)}}, []byte("{\"ok\":true}\n"))
+	assert.NoError(t, err)
+}
+
+func TestHttpCheckJsonAssertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(actuatorHealthForTest))
+	}))
+	defer server.Close()
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+	check := options.HttpCheck{Url: server.URL, JsonAssertions: []options.JsonAssertion{
+		{Path: "status", Equals: ptr("UP")},
+		{Path: "components.db.status", Equals: ptr("UP")},
+		{Path: "components.diskSpace.details.free", Min: ptr(1073741824.0)},
+	}}
+	assert.NoError(t, attemptHttpConnection(t.Context(), check, opts).err)
+
+	// All assertions have to hold
+	check.JsonAssertions = append(check.JsonAssertions, options.JsonAssertion{Path: "components.redis.status", Equals: ptr("UP")})
+	assert.EqualError(t, attemptHttpConnection(t.Context(), check, opts).err, "JSON path components.redis.status not found")
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
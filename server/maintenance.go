@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// MaintenanceStatus reports whether the instance is in maintenance mode, and which of the sources turned it on.
+type MaintenanceStatus struct {
+	Enabled bool `json:"enabled"`
+	// File is true while the sentinel file exists
+	File bool `json:"file"`
+	// Manual is true if maintenance mode was turned on with SIGUSR1 or the admin endpoint
+	Manual bool `json:"manual"`
+}
+
+// maintenanceStatus combines the sources of maintenance mode. The sentinel file is checked on every call, so that it
+// takes effect on the very next request.
+func (state *serverState) maintenanceStatus(opts *options.Options) MaintenanceStatus {
+	status := MaintenanceStatus{Manual: state.maintenance.Load()}
+	if opts.Maintenance.File != "" {
+		if _, err := os.Stat(opts.Maintenance.File); err == nil {
+			status.File = true
+		}
+	}
+	status.Enabled = status.File || status.Manual
+	return status
+}
+
+// setMaintenance turns manual maintenance mode on or off.
+func (state *serverState) setMaintenance(enabled bool) {
+	state.maintenance.Store(enabled)
+}
+
+// toggleMaintenance flips manual maintenance mode and returns whether it is now on.
+func (state *serverState) toggleMaintenance() bool {
+	for {
+		enabled := state.maintenance.Load()
+		if state.maintenance.CompareAndSwap(enabled, !enabled) {
+			return !enabled
+		}
+	}
+}
+
+// watchMaintenanceSignal toggles manual maintenance mode whenever the process receives the maintenance signal, until
+// ctx is canceled. It is a no-op on platforms without such a signal.
+func watchMaintenanceSignal(ctx context.Context, opts *options.Options, state *serverState) {
+	signals := notifyMaintenanceSignal()
+	if signals == nil {
+		return
+	}
+
+	go func() {
+		defer stopMaintenanceSignal(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				if state.toggleMaintenance() {
+					opts.Logger.Infof("Received maintenance signal. Maintenance mode is now on.")
+				} else {
+					opts.Logger.Infof("Received maintenance signal. Maintenance mode is now off.")
+				}
+			}
+		}
+	}()
+}
+
+// adminMaintenanceHandler serves the admin endpoint for maintenance mode: GET reports the current status, POST turns
+// manual maintenance mode on and DELETE turns it off. Requests must be authorized like for the detailed status. The
+// sentinel file can't be removed through this endpoint, which is why the response reports it separately.
+func adminMaintenanceHandler(opts *options.Options, state *serverState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := opts.Logger
+
+		if !authorized(r, opts.Auth) {
+			logger.Warnf("Rejected unauthorized %s %s request from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			logger.Infof("Maintenance mode turned on by %s", r.RemoteAddr)
+			state.setMaintenance(true)
+		case http.MethodDelete:
+			logger.Infof("Maintenance mode turned off by %s", r.RemoteAddr)
+			state.setMaintenance(false)
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		jsonBytes, err := json.Marshal(state.maintenanceStatus(opts))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		err = writeHttpResponse(w, &httpResponse{StatusCode: http.StatusOK, Body: string(jsonBytes), ContentType: "application/json"})
+		if err != nil {
+			logger.Warnf("Failed to send HTTP response: %v", err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/gruntwork-io/health-checker/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenanceFile(t *testing.T) {
+	opts, closeListener := createMaintenanceOptionsForTest(t)
+	defer closeListener()
+
+	opts.Maintenance.File = filepath.Join(t.TempDir(), "maintenance")
+	state := newServerState()
+	readiness := endpointHandler(opts, options.Endpoint{Path: "/readyz", Role: options.EndpointRoleReadiness}, state)
+	liveness := endpointHandler(opts, options.Endpoint{Path: "/livez", Role: options.EndpointRoleLiveness}, state)
+
+	recorder := httptest.NewRecorder()
+	readiness(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	assert.NoError(t, os.WriteFile(opts.Maintenance.File, nil, 0644))
+
+	recorder = httptest.NewRecorder()
+	readiness(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "scheduled upgrade", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	liveness(recorder, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code, "Liveness endpoints must keep passing in maintenance mode")
+
+	assert.NoError(t, os.Remove(opts.Maintenance.File))
+
+	recorder = httptest.NewRecorder()
+	readiness(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestMaintenanceDetailedStatus(t *testing.T) {
+	opts, closeListener := createMaintenanceOptionsForTest(t)
+	defer closeListener()
+
+	opts.DetailedStatus = true
+	state := newServerState()
+	state.setMaintenance(true)
+	handler := endpointHandler(opts, options.Endpoint{Path: "/"}, state)
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	var resp DetailedResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.Equal(t, "scheduled upgrade", resp.Status)
+	assert.Equal(t, MaintenanceStatus{Enabled: true, Manual: true}, resp.Maintenance)
+	assert.Len(t, resp.Checks, 1)
+}
+
+func TestAdminMaintenanceHandler(t *testing.T) {
+	opts, closeListener := createMaintenanceOptionsForTest(t)
+	defer closeListener()
+
+	opts.Admin = true
+	opts.Auth = options.AuthOptions{BearerTokens: []string{"token"}}
+	state := newServerState()
+	handler := adminMaintenanceHandler(opts, state)
+
+	testCases := []struct {
+		name             string
+		method           string
+		token            string
+		expectedCode     int
+		expectedMaintain bool
+	}{
+		{"unauthorized", http.MethodPost, "", http.StatusUnauthorized, false},
+		{"wrong token", http.MethodPost, "guess", http.StatusUnauthorized, false},
+		{"status", http.MethodGet, "token", http.StatusOK, false},
+		{"enable", http.MethodPost, "token", http.StatusOK, true},
+		{"enable twice", http.MethodPost, "token", http.StatusOK, true},
+		{"unsupported method", http.MethodPut, "token", http.StatusMethodNotAllowed, true},
+		{"disable", http.MethodDelete, "token", http.StatusOK, false},
+	}
+
+	// The cases run in order, since each one builds on the state left by the previous one
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := httptest.NewRequest(testCase.method, options.AdminMaintenancePath, nil)
+			if testCase.token != "" {
+				request.Header.Set("Authorization", "Bearer "+testCase.token)
+			}
+			recorder := httptest.NewRecorder()
+			handler(recorder, request)
+
+			assert.Equal(t, testCase.expectedCode, recorder.Code)
+			assert.Equal(t, testCase.expectedMaintain, state.maintenanceStatus(opts).Enabled)
+			if recorder.Code == http.StatusOK {
+				var status MaintenanceStatus
+				assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+				assert.Equal(t, testCase.expectedMaintain, status.Manual)
+			}
+		})
+	}
+}
+
+// createMaintenanceOptionsForTest returns options with a single passing TCP check and a custom maintenance message.
+func createMaintenanceOptionsForTest(t *testing.T) (*options.Options, func()) {
+	ports, err := test.GetFreePorts(1)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+
+	l, err := net.Listen("tcp", test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0]))
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	go handleRequests(t, l, nil)
+
+	opts := createOptionsForTest(t, 5, nil, nil, test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, 0), []string{test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0])})
+	opts.Maintenance = options.MaintenanceOptions{StatusCode: http.StatusServiceUnavailable, Message: "scheduled upgrade"}
+	return opts, func() { closeListeners(t, []net.Listener{l}) }
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package server
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyMaintenanceSignal returns a channel receiving SIGUSR1, which toggles maintenance mode.
+func notifyMaintenanceSignal() chan os.Signal {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	return signals
+}
+
+func stopMaintenanceSignal(signals chan os.Signal) {
+	signal.Stop(signals)
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package server
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenanceSignal(t *testing.T) {
+	opts, closeListener := createMaintenanceOptionsForTest(t)
+	defer closeListener()
+
+	state := newServerState()
+	ctx := t.Context()
+	watchMaintenanceSignal(ctx, opts, state)
+
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool {
+		return state.maintenanceStatus(opts).Manual
+	}, 5*time.Second, 10*time.Millisecond, "SIGUSR1 did not turn maintenance mode on")
+
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool {
+		return !state.maintenanceStatus(opts).Manual
+	}, 5*time.Second, 10*time.Millisecond, "SIGUSR1 did not turn maintenance mode off")
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package server
+
+import (
+	"os"
+)
+
+// notifyMaintenanceSignal returns nil, since Windows has no SIGUSR1. Maintenance mode can still be toggled with the
+// sentinel file or the admin endpoint.
+func notifyMaintenanceSignal() chan os.Signal {
+	return nil
+}
+
+func stopMaintenanceSignal(signals chan os.Signal) {}
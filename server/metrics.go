@@ -0,0 +1,265 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the check duration histogram. They extend the usual Prometheus
+// defaults up to a minute, since scripts are commonly given long timeouts.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// metrics collects the time series exported on the Prometheus /metrics endpoint. The exposition format is simple enough
+// that it is written by hand rather than pulling in the Prometheus client library.
+type metrics struct {
+	mu            sync.Mutex
+	checks        map[string]*checkMetrics
+	sharedResults map[string]uint64
+	requests      map[requestKey]uint64
+}
+
+// checkMetrics holds the series of a single check, keyed by its name in metrics.checks.
+type checkMetrics struct {
+	kind     string
+	healthy  bool
+	failures uint64
+	// buckets counts the runs that took at most the duration of the bucket with the same index in durationBuckets
+	buckets []uint64
+	sum     float64
+	count   uint64
+	// values are the metrics reported by the latest run of the check
+	values []Metric
+	// subChecks are the sub-checks reported by the latest run of the check, by a script in json mode
+	subChecks []SubCheckResult
+}
+
+type requestKey struct {
+	path string
+	code int
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		checks:        map[string]*checkMetrics{},
+		sharedResults: map[string]uint64{},
+		requests:      map[requestKey]uint64{},
+	}
+}
+
+// observeCheck records a completed run of the given check: how long it took, the metrics it reported, whether it
+// failed, and whether the check is reported as healthy after damping.
+func (m *metrics) observeCheck(c check, result CheckResult, failed bool, healthy bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	series, ok := m.checks[c.name]
+	if !ok {
+		series = &checkMetrics{kind: c.kind, buckets: make([]uint64, len(durationBuckets))}
+		m.checks[c.name] = series
+	}
+
+	seconds := result.elapsed.Seconds()
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			series.buckets[i]++
+		}
+	}
+	series.sum += seconds
+	series.count++
+
+	if failed {
+		series.failures++
+	}
+	series.healthy = healthy
+	series.values = uniqueMetrics(result.Metrics)
+	series.subChecks = uniqueSubChecks(result.Checks)
+}
+
+// uniqueMetrics returns the given metrics with a single entry for every name and unit, which holds the value that was
+// reported last, since Prometheus rejects a scrape with duplicate series. Entries are kept in the order in which their
+// name and unit were first reported.
+func uniqueMetrics(values []Metric) []Metric {
+	type metricKey struct{ name, unit string }
+	var unique []Metric
+	positions := map[metricKey]int{}
+	for _, value := range values {
+		key := metricKey{value.Name, value.Unit}
+		if i, ok := positions[key]; ok {
+			unique[i] = value
+			continue
+		}
+		positions[key] = len(unique)
+		unique = append(unique, value)
+	}
+	return unique
+}
+
+// uniqueSubChecks returns the given sub-checks with a single entry for every name, which holds the status that was
+// reported last, for the same reason as uniqueMetrics.
+func uniqueSubChecks(subChecks []SubCheckResult) []SubCheckResult {
+	var unique []SubCheckResult
+	positions := map[string]int{}
+	for _, subCheck := range subChecks {
+		if i, ok := positions[subCheck.Name]; ok {
+			unique[i] = subCheck
+			continue
+		}
+		positions[subCheck.Name] = len(unique)
+		unique = append(unique, subCheck)
+	}
+	return unique
+}
+
+// countSharedResult records a request to the given endpoint that was answered with a singleflight result shared with
+// other concurrent requests.
+func (m *metrics) countSharedResult(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sharedResults[path]++
+}
+
+// countRequest records an inbound request to the given endpoint and the status code it was answered with.
+func (m *metrics) countRequest(path string, code int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestKey{path, code}]++
+}
+
+// write renders all series in the Prometheus text exposition format, sorted so that the output is stable.
+func (m *metrics) write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := bufio.NewWriter(w)
+
+	names := make([]string, 0, len(m.checks))
+	for name := range m.checks {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	writeHeader(out, "health_checker_check_status", "gauge", "Whether the check is currently passing (1) or failing (0), after flap damping.")
+	for _, name := range names {
+		series := m.checks[name]
+		value := 0
+		if series.healthy {
+			value = 1
+		}
+		fmt.Fprintf(out, "health_checker_check_status{%s} %d\n", checkLabels(name, series.kind), value)
+	}
+
+	writeHeader(out, "health_checker_check_failures_total", "counter", "Number of runs of the check that failed, before flap damping.")
+	for _, name := range names {
+		series := m.checks[name]
+		fmt.Fprintf(out, "health_checker_check_failures_total{%s} %d\n", checkLabels(name, series.kind), series.failures)
+	}
+
+	writeHeader(out, "health_checker_check_duration_seconds", "histogram", "Duration of the runs of the check, in seconds.")
+	for _, name := range names {
+		series := m.checks[name]
+		labels := checkLabels(name, series.kind)
+		for i, bound := range durationBuckets {
+			fmt.Fprintf(out, "health_checker_check_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, formatFloat(bound), series.buckets[i])
+		}
+		fmt.Fprintf(out, "health_checker_check_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, series.count)
+		fmt.Fprintf(out, "health_checker_check_duration_seconds_sum{%s} %s\n", labels, formatFloat(series.sum))
+		fmt.Fprintf(out, "health_checker_check_duration_seconds_count{%s} %d\n", labels, series.count)
+	}
+
+	writeHeader(out, "health_checker_check_metric", "gauge", "Latest value of the metrics reported by the check, such as the performance data of Nagios plugins.")
+	for _, name := range names {
+		series := m.checks[name]
+		labels := checkLabels(name, series.kind)
+		for _, value := range series.values {
+			fmt.Fprintf(out, "health_checker_check_metric{%s,metric=\"%s\",unit=\"%s\"} %s\n", labels, escapeLabelValue(value.Name), escapeLabelValue(value.Unit), formatFloat(value.Value))
+		}
+	}
+
+	writeHeader(out, "health_checker_subcheck_status", "gauge", "Whether the sub-check reported by the latest run of the check is passing (1) or failing (0).")
+	for _, name := range names {
+		series := m.checks[name]
+		labels := checkLabels(name, series.kind)
+		for _, subCheck := range series.subChecks {
+			value := 1
+			if subCheck.Status == CheckStatusFailing {
+				value = 0
+			}
+			fmt.Fprintf(out, "health_checker_subcheck_status{%s,subcheck=\"%s\"} %d\n", labels, escapeLabelValue(subCheck.Name), value)
+		}
+	}
+
+	paths := make([]string, 0, len(m.sharedResults))
+	for path := range m.sharedResults {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	writeHeader(out, "health_checker_singleflight_shared_total", "counter", "Number of requests answered with a singleflight result shared with other concurrent requests.")
+	for _, path := range paths {
+		fmt.Fprintf(out, "health_checker_singleflight_shared_total{endpoint=\"%s\"} %d\n", escapeLabelValue(path), m.sharedResults[path])
+	}
+
+	keys := make([]requestKey, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b requestKey) int {
+		if a.path != b.path {
+			return strings.Compare(a.path, b.path)
+		}
+		return a.code - b.code
+	})
+
+	writeHeader(out, "health_checker_requests_total", "counter", "Number of inbound health check requests, by endpoint and response status code.")
+	for _, key := range keys {
+		fmt.Fprintf(out, "health_checker_requests_total{endpoint=\"%s\",code=\"%d\"} %d\n", escapeLabelValue(key.path), key.code, m.requests[key])
+	}
+
+	return out.Flush()
+}
+
+func writeHeader(out io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func checkLabels(name string, kind string) string {
+	return fmt.Sprintf("check=\"%s\",type=\"%s\"", escapeLabelValue(name), escapeLabelValue(kind))
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabelValue escapes a label value as required by the exposition format. Check names derived from script
+// command lines may well contain quotes or backslashes.
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// metricsHandler serves the metrics collected in the server state in the Prometheus text exposition format. Since the
+// labels of unnamed checks reveal their targets, requests must be authorized like for the detailed status.
+func metricsHandler(opts *options.Options, state *serverState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, opts.Auth) {
+			opts.Logger.Warnf("Rejected unauthorized %s %s request from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		err := state.metrics.write(w)
+		if err != nil {
+			opts.Logger.Warnf("Failed to write metrics: %v", err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/gruntwork-io/health-checker/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	app := check{name: "app", kind: options.CheckTypeTcp, failureThreshold: 2, successThreshold: 1}
+	script := check{name: `/bin/check "db"`, kind: options.CheckTypeScript, failureThreshold: 1, successThreshold: 1}
+	plugin := check{name: "ping", kind: options.CheckTypeScript, failureThreshold: 1, successThreshold: 1}
+
+	state := newServerState()
+	state.record(app, CheckResult{Status: CheckStatusPassing, elapsed: 3 * time.Millisecond}, time.Now())
+	// The first failure is absorbed by the threshold, so the check is still reported as passing
+	state.record(app, CheckResult{Status: CheckStatusFailing, elapsed: 2 * time.Second}, time.Now())
+	state.record(script, CheckResult{Status: CheckStatusFailing, elapsed: 40 * time.Millisecond}, time.Now())
+	state.record(plugin, CheckResult{Status: CheckStatusWarning, Metrics: []Metric{{Name: "rta", Value: 150, Unit: "ms"}, {Name: "pl", Value: 0, Unit: "%"}}}, time.Now())
+	// Canceled runs are not observed
+	state.record(script, CheckResult{Status: CheckStatusCanceled, elapsed: time.Millisecond}, time.Now())
+	state.metrics.countRequest("/", http.StatusOK)
+	state.metrics.countRequest("/", http.StatusOK)
+	state.metrics.countRequest("/", http.StatusGatewayTimeout)
+	state.metrics.countSharedResult("/")
+
+	opts := createOptionsForTest(t, 5, nil, nil, test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, 0), nil)
+	recorder := httptest.NewRecorder()
+	metricsHandler(opts, state)(recorder, httptest.NewRequest(http.MethodGet, options.MetricsPath, nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+	body := recorder.Body.String()
+	for _, expected := range []string{
+		"# TYPE health_checker_check_status gauge",
+		`health_checker_check_status{check="/bin/check \"db\"",type="script"} 0`,
+		`health_checker_check_status{check="app",type="tcp"} 1`,
+		`health_checker_check_status{check="ping",type="script"} 1`,
+		"# TYPE health_checker_check_failures_total counter",
+		`health_checker_check_failures_total{check="/bin/check \"db\"",type="script"} 1`,
+		`health_checker_check_failures_total{check="app",type="tcp"} 1`,
+		"# TYPE health_checker_check_duration_seconds histogram",
+		`health_checker_check_duration_seconds_bucket{check="app",type="tcp",le="0.005"} 1`,
+		`health_checker_check_duration_seconds_bucket{check="app",type="tcp",le="1"} 1`,
+		`health_checker_check_duration_seconds_bucket{check="app",type="tcp",le="2.5"} 2`,
+		`health_checker_check_duration_seconds_bucket{check="app",type="tcp",le="+Inf"} 2`,
+		`health_checker_check_duration_seconds_sum{check="app",type="tcp"} 2.003`,
+		`health_checker_check_duration_seconds_count{check="app",type="tcp"} 2`,
+		`health_checker_check_duration_seconds_count{check="/bin/check \"db\"",type="script"} 1`,
+		`health_checker_check_failures_total{check="ping",type="script"} 0`,
+		"# TYPE health_checker_check_metric gauge",
+		`health_checker_check_metric{check="ping",type="script",metric="rta",unit="ms"} 150`,
+		`health_checker_check_metric{check="ping",type="script",metric="pl",unit="%"} 0`,
+		`health_checker_singleflight_shared_total{endpoint="/"} 1`,
+		`health_checker_requests_total{endpoint="/",code="200"} 2`,
+		`health_checker_requests_total{endpoint="/",code="504"} 1`,
+	} {
+		assert.Contains(t, body, expected)
+	}
+
+	// Series are sorted, so that the output is stable between scrapes
+	assert.Less(t, strings.Index(body, `health_checker_check_status{check="/bin/check`), strings.Index(body, `health_checker_check_status{check="app"`))
+}
+
+func TestMetricsDeduplicateReportedValues(t *testing.T) {
+	plugin := check{name: "ping", kind: options.CheckTypeScript, failureThreshold: 1, successThreshold: 1}
+	state := newServerState()
+	state.record(plugin, CheckResult{Status: CheckStatusPassing, Metrics: []Metric{
+		{Name: "rta", Value: 150, Unit: "ms"},
+		{Name: "pl", Value: 0, Unit: "%"},
+		{Name: "rta", Value: 175, Unit: "ms"},
+		{Name: "rta", Value: 0.2, Unit: "s"},
+	}}, time.Now())
+
+	var body strings.Builder
+	assert.NoError(t, state.metrics.write(&body))
+	assert.Equal(t, 1, strings.Count(body.String(), `health_checker_check_metric{check="ping",type="script",metric="rta",unit="ms"}`))
+	assert.Contains(t, body.String(), `health_checker_check_metric{check="ping",type="script",metric="rta",unit="ms"} 175`)
+	assert.Contains(t, body.String(), `health_checker_check_metric{check="ping",type="script",metric="rta",unit="s"} 0.2`)
+	assert.Contains(t, body.String(), `health_checker_check_metric{check="ping",type="script",metric="pl",unit="%"} 0`)
+}
+
+func TestMetricsExportSubChecks(t *testing.T) {
+	replication := check{name: "replication", kind: options.CheckTypeScript, failureThreshold: 1, successThreshold: 1}
+	state := newServerState()
+	state.record(replication, CheckResult{Status: CheckStatusWarning, Checks: []SubCheckResult{
+		{Name: "replica-1", Status: CheckStatusPassing},
+		{Name: "replica-2", Status: CheckStatusWarning},
+		{Name: "replica-3", Status: CheckStatusPassing},
+		{Name: "replica-3", Status: CheckStatusFailing, Message: "lag is 120s"},
+	}}, time.Now())
+
+	var body strings.Builder
+	assert.NoError(t, state.metrics.write(&body))
+	assert.Contains(t, body.String(), "# TYPE health_checker_subcheck_status gauge")
+	assert.Contains(t, body.String(), `health_checker_subcheck_status{check="replication",type="script",subcheck="replica-1"} 1`)
+	assert.Contains(t, body.String(), `health_checker_subcheck_status{check="replication",type="script",subcheck="replica-2"} 1`)
+	assert.Contains(t, body.String(), `health_checker_subcheck_status{check="replication",type="script",subcheck="replica-3"} 0`)
+	assert.Equal(t, 1, strings.Count(body.String(), `subcheck="replica-3"`))
+}
+
+func TestEndpointHandlerCountsRequests(t *testing.T) {
+	ports, err := test.GetFreePorts(1)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+
+	// Nothing listens on the port, so every request fails
+	opts := createOptionsForTest(t, 5, nil, nil, test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, 0), []string{test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0])})
+	endpoint := options.Endpoint{Path: "/readyz"}
+	state := newServerState()
+	handler := endpointHandler(opts, endpoint, state)
+
+	for range 2 {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodGet, endpoint.Path, nil))
+		assert.Equal(t, http.StatusGatewayTimeout, recorder.Code)
+	}
+
+	recorder := httptest.NewRecorder()
+	metricsHandler(opts, state)(recorder, httptest.NewRequest(http.MethodGet, options.MetricsPath, nil))
+	assert.Contains(t, recorder.Body.String(), `health_checker_requests_total{endpoint="/readyz",code="504"} 2`)
+	assert.Contains(t, recorder.Body.String(), `health_checker_check_failures_total{check="`+opts.Ports[0].Label()+`",type="tcp"} 2`)
+}
+
+func TestMetricsRequireAuthorization(t *testing.T) {
+	opts := createOptionsForTest(t, 5, nil, nil, test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, 0), nil)
+	opts.Auth = options.AuthOptions{BearerTokens: []string{"s3cret"}}
+	handler := metricsHandler(opts, newServerState())
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, options.MetricsPath, nil))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "health_checker_")
+
+	request := httptest.NewRequest(http.MethodGet, options.MetricsPath, nil)
+	request.Header.Set("Authorization", "Bearer s3cret")
+	recorder = httptest.NewRecorder()
+	handler(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The exit statuses of a Nagios plugin.
+const (
+	nagiosOk       = 0
+	nagiosWarning  = 1
+	nagiosCritical = 2
+	nagiosUnknown  = 3
+)
+
+var nagiosStateNames = map[int]string{
+	nagiosOk:       "OK",
+	nagiosWarning:  "WARNING",
+	nagiosCritical: "CRITICAL",
+	nagiosUnknown:  "UNKNOWN",
+}
+
+// nagiosOutcome interprets the stdout and exit error of a script run in nagios mode. OK passes, WARNING degrades the
+// check, and CRITICAL, UNKNOWN and any other exit status fail it. Errors that are not an exit status, such as the
+// script not being executable, fail the check as they would in exit_code mode.
+func nagiosOutcome(stdout string, err error) checkOutcome {
+	exitCode := nagiosOk
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return checkOutcome{output: stdout, err: err}
+		}
+		exitCode = exitErr.ExitCode()
+		// A plugin that crashes may only have written to stderr
+		if strings.TrimSpace(stdout) == "" {
+			stdout = string(exitErr.Stderr)
+		}
+	}
+
+	text, longText, perfData := parseNagiosOutput(stdout)
+	outcome := checkOutcome{output: text, metrics: parsePerfData(perfData)}
+	if longText != "" {
+		outcome.output = text + "\n" + longText
+	}
+
+	_, ok := nagiosStateNames[exitCode]
+	switch {
+	case !ok && exitCode < 0:
+		// Killed by a signal, most likely because the script timed out
+		outcome.err = err
+	case !ok:
+		outcome.err = fmt.Errorf("exit status %d is not a Nagios plugin state: %s", exitCode, text)
+	default:
+		outcome.err = nagiosStateError(exitCode, text)
+		outcome.warning = exitCode == nagiosWarning
+	}
+	return outcome
+}
+
+// nagiosStateError returns the error reported for the given Nagios plugin state and status text. OK has no error.
+func nagiosStateError(state int, text string) error {
+	if state == nagiosOk {
+		return nil
+	}
+	if text == "" {
+		return errors.New(nagiosStateNames[state])
+	}
+	return fmt.Errorf("%s: %s", nagiosStateNames[state], text)
+}
+
+// parseNagiosOutput splits the output of a Nagios plugin into the status text of the first line, the long text of the
+// following lines, and the performance data. Performance data may follow a "|" on the first line, and a "|" within the
+// long text starts more performance data that runs until the end of the output.
+func parseNagiosOutput(output string) (text string, longText string, perfData string) {
+	firstLine, rest, _ := strings.Cut(strings.TrimRight(output, "\r\n"), "\n")
+	text, perfData, _ = strings.Cut(firstLine, "|")
+	longText, morePerfData, _ := strings.Cut(rest, "|")
+	perfData = strings.TrimSpace(strings.TrimSpace(perfData) + " " + strings.TrimSpace(morePerfData))
+	return strings.TrimSpace(text), strings.TrimSpace(longText), perfData
+}
+
+// perfDataValuePattern matches the value of a performance data entry and its optional unit of measure. Some plugins
+// use a decimal comma, which is accepted too.
+var perfDataValuePattern = regexp.MustCompile(`^([-+]?[0-9]*[.,]?[0-9]+(?:[eE][-+]?[0-9]+)?)([a-zA-Z%]*)$`)
+
+// parsePerfData parses Nagios performance data of the form 'label'=value[UOM];[warn];[crit];[min];[max], with entries
+// separated by spaces. Labels containing spaces are quoted with single quotes, which are escaped by doubling them.
+// Entries that can't be parsed, as well as undetermined "U" values, are skipped rather than failing the check.
+func parsePerfData(perfData string) []Metric {
+	var metrics []Metric
+	for _, entry := range splitPerfData(perfData) {
+		separator := strings.LastIndex(entry, "=")
+		if separator <= 0 {
+			continue
+		}
+		label := entry[:separator]
+		if len(label) >= 2 && strings.HasPrefix(label, "'") && strings.HasSuffix(label, "'") {
+			label = strings.ReplaceAll(label[1:len(label)-1], "''", "'")
+		}
+
+		fields := strings.Split(entry[separator+1:], ";")
+		match := perfDataValuePattern.FindStringSubmatch(fields[0])
+		if match == nil {
+			continue
+		}
+		value, err := parsePerfDataNumber(match[1])
+		if err != nil {
+			continue
+		}
+
+		metric := Metric{Name: label, Value: value, Unit: match[2]}
+		if len(fields) > 1 {
+			metric.Warning = fields[1]
+		}
+		if len(fields) > 2 {
+			metric.Critical = fields[2]
+		}
+		if len(fields) > 3 {
+			metric.Min = parseOptionalNumber(fields[3])
+		}
+		if len(fields) > 4 {
+			metric.Max = parseOptionalNumber(fields[4])
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+// splitPerfData splits performance data into its entries at whitespace outside of quoted labels.
+func splitPerfData(perfData string) []string {
+	var entries []string
+	var current strings.Builder
+	quoted := false
+	for _, r := range perfData {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+			current.WriteRune(r)
+		case !quoted && (r == ' ' || r == '\t' || r == '\n' || r == '\r'):
+			if current.Len() > 0 {
+				entries = append(entries, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		entries = append(entries, current.String())
+	}
+	return entries
+}
+
+func parsePerfDataNumber(value string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+}
+
+// parseOptionalNumber parses the min or max field of a performance data entry, which may be empty.
+func parseOptionalNumber(value string) *float64 {
+	number, err := parsePerfDataNumber(value)
+	if err != nil {
+		return nil
+	}
+	return &number
+}
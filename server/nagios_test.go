@@ -0,0 +1,126 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNagiosOutput(t *testing.T) {
+	testCases := []struct {
+		name             string
+		output           string
+		expectedText     string
+		expectedLongText string
+		expectedPerfData string
+	}{
+		{"text only", "DISK OK\n", "DISK OK", "", ""},
+		{"empty", "", "", "", ""},
+		{"perfdata on first line", "DISK OK - free space: / 3326 MB | /=2643MB;5948;5958;0;5968\n", "DISK OK - free space: / 3326 MB", "", "/=2643MB;5948;5958;0;5968"},
+		{
+			"long text and more perfdata",
+			"DISK OK - free space: / 3326 MB | /=2643MB;5948;5958;0;5968\n/ 15272 MB (77%);\n/boot 68 MB (69%); | /boot=68MB;88;93;0;98\n/home=69357MB;253404;253409;0;253414\n",
+			"DISK OK - free space: / 3326 MB",
+			"/ 15272 MB (77%);\n/boot 68 MB (69%);",
+			"/=2643MB;5948;5958;0;5968 /boot=68MB;88;93;0;98\n/home=69357MB;253404;253409;0;253414",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			text, longText, perfData := parseNagiosOutput(testCase.output)
+			assert.Equal(t, testCase.expectedText, text)
+			assert.Equal(t, testCase.expectedLongText, longText)
+			assert.Equal(t, testCase.expectedPerfData, perfData)
+		})
+	}
+}
+
+func TestParsePerfData(t *testing.T) {
+	zero := 0.0
+	hundred := 100.0
+
+	testCases := []struct {
+		name     string
+		perfData string
+		expected []Metric
+	}{
+		{"empty", "", nil},
+		{"value only", "time=0.006s", []Metric{{Name: "time", Value: 0.006, Unit: "s"}}},
+		{
+			"all fields",
+			"load1=0.42;5:10;@10:20;0;100",
+			[]Metric{{Name: "load1", Value: 0.42, Warning: "5:10", Critical: "@10:20", Min: &zero, Max: &hundred}},
+		},
+		{
+			"multiple entries",
+			"rta=0.044ms;3000;5000;0 pl=0%;80;100;;",
+			[]Metric{{Name: "rta", Value: 0.044, Unit: "ms", Warning: "3000", Critical: "5000", Min: &zero}, {Name: "pl", Value: 0, Unit: "%", Warning: "80", Critical: "100"}},
+		},
+		{"quoted label", "'free space'=12GB 'it''s'=1", []Metric{{Name: "free space", Value: 12, Unit: "GB"}, {Name: "it's", Value: 1}}},
+		{"decimal comma", "time=1,5s", []Metric{{Name: "time", Value: 1.5, Unit: "s"}}},
+		{"negative value", "temp=-4.5C", []Metric{{Name: "temp", Value: -4.5, Unit: "C"}}},
+		{"undetermined value is skipped", "time=U;1;2 up=1", []Metric{{Name: "up", Value: 1}}},
+		{"malformed entries are skipped", "garbage =5 x=abc y=2", []Metric{{Name: "y", Value: 2}}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, parsePerfData(testCase.perfData))
+		})
+	}
+}
+
+func TestNagiosScriptChecks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Uses POSIX shell scripts")
+	}
+
+	dir := t.TempDir()
+	testCases := []struct {
+		name            string
+		script          string
+		severity        string
+		expectedStatus  string
+		expectedError   string
+		expectedCode    int
+		expectedBody    string
+		expectedMetrics []Metric
+	}{
+		{"ok", "#!/bin/sh\necho 'PING OK - rta 0.05ms | rta=0.05ms;100;500'\nexit 0\n", "", CheckStatusPassing, "", http.StatusOK, "OK", []Metric{{Name: "rta", Value: 0.05, Unit: "ms", Warning: "100", Critical: "500"}}},
+		{"warning degrades", "#!/bin/sh\necho 'PING WARNING - rta 150ms | rta=150ms;100;500'\nexit 1\n", "", CheckStatusWarning, "WARNING: PING WARNING - rta 150ms", http.StatusOK, "degraded", []Metric{{Name: "rta", Value: 150, Unit: "ms", Warning: "100", Critical: "500"}}},
+		{"warning of informational check is ignored", "#!/bin/sh\necho 'PING WARNING'\nexit 1\n", options.SeverityInfo, CheckStatusWarning, "WARNING: PING WARNING", http.StatusOK, "OK", nil},
+		{"critical fails", "#!/bin/sh\necho 'PING CRITICAL - host unreachable'\nexit 2\n", "", CheckStatusFailing, "CRITICAL: PING CRITICAL - host unreachable", http.StatusGatewayTimeout, "At least one health check failed", nil},
+		{"unknown fails", "#!/bin/sh\necho 'PING UNKNOWN - invalid host'\nexit 3\n", "", CheckStatusFailing, "UNKNOWN: PING UNKNOWN - invalid host", http.StatusGatewayTimeout, "At least one health check failed", nil},
+		{"unexpected exit status fails", "#!/bin/sh\necho 'oops'\nexit 42\n", "", CheckStatusFailing, "exit status 42 is not a Nagios plugin state: oops", http.StatusGatewayTimeout, "At least one health check failed", nil},
+		{"stderr is used without stdout", "#!/bin/sh\necho 'line 3: syntax error' >&2\nexit 2\n", "", CheckStatusFailing, "CRITICAL: line 3: syntax error", http.StatusGatewayTimeout, "At least one health check failed", nil},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			script := createDummyScript(t, dir, fmt.Sprintf("plugin_%d", i), testCase.script)
+			opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+			scripts, err := options.ParseScripts([]string{script})
+			assert.NoError(t, err)
+			scripts[0].Mode = options.ScriptModeNagios
+			scripts[0].Severity = testCase.severity
+			opts.Scripts = scripts
+
+			state := newServerState()
+			pass := runChecks(opts, options.Endpoint{Path: "/"}, state)
+			assert.Len(t, pass.results, 1)
+			result := pass.results[0]
+			assert.Equal(t, testCase.expectedStatus, result.Status)
+			assert.Equal(t, testCase.expectedError, result.Error)
+			assert.Equal(t, testCase.expectedMetrics, result.Metrics)
+
+			resp := buildResponse(opts, options.Endpoint{Path: "/"}, pass, false, MaintenanceStatus{})
+			assert.Equal(t, testCase.expectedCode, resp.StatusCode)
+			assert.Equal(t, testCase.expectedBody, resp.Body)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// Report is the outcome of a single pass over the checks, as produced by RunChecksOnce.
+type Report struct {
+	DetailedResponse
+	// Failed is true if at least one critical check failed
+	Failed bool `json:"-"`
+	// Degraded is true if no critical check failed, but at least one warning check did
+	Degraded bool `json:"-"`
+}
+
+// RunChecksOnce runs every configured check a single time, exactly as a request to the default endpoint would, but
+// without opening a listener. Background and singleflight mode have no meaning for a single pass and are ignored.
+func RunChecksOnce(opts *options.Options) Report {
+	endpoint := options.Endpoint{Path: options.DefaultEndpointPath, RunAll: opts.RunAll}
+	pass := runChecks(opts, endpoint, newServerState())
+
+	failed, degraded, errorMessages := summarize(pass.results)
+	return Report{
+		DetailedResponse: DetailedResponse{
+			Status:      summaryText(failed, degraded),
+			ElapsedTime: pass.elapsed.String(),
+			Errors:      errorMessages,
+			Checks:      pass.results,
+		},
+		Failed:   failed,
+		Degraded: degraded,
+	}
+}
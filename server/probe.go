@@ -0,0 +1,120 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// probeTcpConnection sends the payload of the port check on the connection and, if the check expects a response,
+// reads until the response meets the expectations. It gives up once the connection is closed, MaxBytes were read, or
+// the read timeout expires. The read timeout is capped by the deadline of the context, so that the exchange never
+// outlasts the timeout of the check. The response is returned as the output, so that it shows up in the detailed
+// status.
+func probeTcpConnection(ctx context.Context, conn net.Conn, port options.PortCheck, opts *options.Options) checkOutcome {
+	if len(port.Send) == 0 && !port.AwaitsResponse() {
+		return checkOutcome{}
+	}
+	expect, err := compileProbeExpect(port.Probe)
+	if err != nil {
+		return checkOutcome{err: err}
+	}
+
+	readTimeout := tcpTimeout(port, opts)
+	if port.ReadTimeoutMs > 0 {
+		readTimeout = time.Duration(port.ReadTimeoutMs) * time.Millisecond
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		readTimeout = min(readTimeout, time.Until(deadline).Round(time.Millisecond))
+	}
+	if err := conn.SetDeadline(time.Now().Add(readTimeout)); err != nil {
+		return checkOutcome{err: err}
+	}
+	// Unblock the reads and writes below as soon as the check is canceled
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if len(port.Send) > 0 {
+		if _, err := conn.Write(port.Send); err != nil {
+			return checkOutcome{err: err}
+		}
+	}
+	if !port.AwaitsResponse() {
+		return checkOutcome{}
+	}
+
+	maxBytes := port.EffectiveMaxBytes()
+	var response []byte
+	buffer := make([]byte, min(maxBytes, 4096))
+	for {
+		n, err := conn.Read(buffer[:min(len(buffer), maxBytes-len(response))])
+		response = append(response, buffer[:n]...)
+		mismatch := verifyProbeResponse(port.Probe, expect, response)
+
+		var failure error
+		var netErr net.Error
+		switch {
+		case mismatch == nil:
+			return checkOutcome{output: describePayload(response)}
+		case errors.Is(err, io.EOF):
+			failure = fmt.Errorf("connection closed before the expected response: %w", mismatch)
+		case errors.As(err, &netErr) && netErr.Timeout():
+			failure = fmt.Errorf("no expected response within %v: %w", readTimeout, mismatch)
+		case err != nil:
+			failure = err
+		case len(response) >= maxBytes:
+			failure = fmt.Errorf("no expected response within the first %d bytes: %w", maxBytes, mismatch)
+		default:
+			// The rest of the response may still be on its way
+			continue
+		}
+		return checkOutcome{output: describePayload(response), err: failure}
+	}
+}
+
+// compileProbeExpect compiles the regex the response of the probe must match, or returns nil if it has none.
+func compileProbeExpect(probe options.Probe) (*regexp.Regexp, error) {
+	if probe.Expect == "" {
+		return nil, nil
+	}
+	expect, err := regexp.Compile(probe.Expect)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expect regex '%s': %w", probe.Expect, err)
+	}
+	return expect, nil
+}
+
+// verifyProbeResponse returns an error if the response doesn't match the expectations of the probe. The expect regex
+// is the probe's Expect, as compiled by compileProbeExpect.
+func verifyProbeResponse(probe options.Probe, expect *regexp.Regexp, response []byte) error {
+	if expect != nil {
+		if !expect.Match(response) {
+			return fmt.Errorf("response %s doesn't match %s", describePayload(response), probe.Expect)
+		}
+	}
+	if len(probe.ExpectBytes) > 0 && !bytes.Contains(response, probe.ExpectBytes) {
+		return fmt.Errorf("response %s doesn't contain %s", describePayload(response), hex.EncodeToString(probe.ExpectBytes))
+	}
+	return nil
+}
+
+// describePayload returns the payload as quoted text if it is printable ASCII, and hex encoded otherwise, so that
+// binary responses can be reported in logs and the detailed status.
+func describePayload(payload []byte) string {
+	for _, b := range payload {
+		if (b < 0x20 || b > 0x7e) && b != '\t' && b != '\r' && b != '\n' {
+			return "0x" + hex.EncodeToString(payload)
+		}
+	}
+	return fmt.Sprintf("%q", payload)
+}
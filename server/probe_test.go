@@ -0,0 +1,150 @@
+package server
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTcpProbes(t *testing.T) {
+	banner := startTcpServerForTest(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte("220 smtp"))
+		time.Sleep(50 * time.Millisecond)
+		_, _ = conn.Write([]byte(" ESMTP ready\r\n"))
+		time.Sleep(time.Second)
+	})
+	echo := startTcpServerForTest(t, func(conn net.Conn) {
+		_, _ = io.Copy(conn, conn)
+	})
+	refusing := startTcpServerForTest(t, func(conn net.Conn) {
+		_, _ = conn.Read(make([]byte, 64))
+		_, _ = conn.Write([]byte("-ERR max clients reached\r\n"))
+	})
+	silent := startTcpServerForTest(t, func(conn net.Conn) {
+		time.Sleep(time.Second)
+	})
+
+	testCases := []struct {
+		name           string
+		port           options.PortCheck
+		expectedError  string
+		expectedOutput string
+	}{
+		{"connect only", options.PortCheck{Address: silent}, "", ""},
+		{"send only", options.PortCheck{Address: silent, Probe: options.Probe{Send: []byte("QUIT\r\n")}}, "", ""},
+		{"banner", options.PortCheck{Address: banner, Probe: options.Probe{Expect: `^220 .* ESMTP`}}, "", `"220 smtp ESMTP ready\r\n"`},
+		{"text exchange", options.PortCheck{Address: echo, Probe: options.Probe{Send: []byte("PING\r\n"), Expect: `^PING\r\n$`}}, "", `"PING\r\n"`},
+		{"bytes exchange", options.PortCheck{Address: echo, Probe: options.Probe{Send: []byte{0x00, 0x01, 0x02}, ExpectBytes: []byte{0x01, 0x02}}}, "", "0x000102"},
+		{"connection closed", options.PortCheck{Address: refusing, Probe: options.Probe{Send: []byte("PING\r\n"), Expect: `^\+PONG`}}, "connection closed before the expected response: response \"-ERR max clients reached\\r\\n\" doesn't match ^\\+PONG", `"-ERR max clients reached\r\n"`},
+		{"read timeout", options.PortCheck{Address: silent, Probe: options.Probe{Expect: "^SSH-2.0-"}, ReadTimeoutMs: 100}, `no expected response within 100ms: response "" doesn't match ^SSH-2.0-`, `""`},
+		{"invalid expect", options.PortCheck{Address: banner, Probe: options.Probe{Expect: "("}}, "invalid expect regex '(': error parsing regexp: missing closing ): `(`", ""},
+		{"max bytes", options.PortCheck{Address: banner, Probe: options.Probe{Expect: "ESMTP"}, MaxBytes: 5}, `no expected response within the first 5 bytes: response "220 s" doesn't match ESMTP`, `"220 s"`},
+	}
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			outcome := attemptTcpConnection(t.Context(), testCase.port, opts)
+			if testCase.expectedError == "" {
+				assert.NoError(t, outcome.err)
+			} else {
+				assert.EqualError(t, outcome.err, testCase.expectedError)
+			}
+			assert.Equal(t, testCase.expectedOutput, outcome.output)
+		})
+	}
+}
+
+func TestTcpProbeHonorsCheckTimeout(t *testing.T) {
+	stalled := startTcpServerForTest(t, func(conn net.Conn) {
+		time.Sleep(3 * time.Second)
+	})
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+	port := options.PortCheck{CheckOptions: options.CheckOptions{Timeout: 1}, Address: stalled, Probe: options.Probe{Expect: "^SSH-2.0-"}, ReadTimeoutMs: 10000}
+
+	start := time.Now()
+	outcome := attemptTcpConnection(t.Context(), port, opts)
+	assert.ErrorContains(t, outcome.err, "no expected response within")
+	assert.Less(t, time.Since(start), 1500*time.Millisecond)
+}
+
+func TestDescribePayload(t *testing.T) {
+	testCases := []struct {
+		name     string
+		payload  []byte
+		expected string
+	}{
+		{"text", []byte("220 mail.example.com ESMTP"), `"220 mail.example.com ESMTP"`},
+		{"text with line breaks", []byte("+PONG\r\n"), `"+PONG\r\n"`},
+		{"empty", []byte{}, `""`},
+		{"binary", []byte{0x00, 0x01, 0xff}, "0x0001ff"},
+		{"control characters", []byte("ok\x07"), "0x6f6b07"},
+		{"non-ASCII", []byte{0xde, 0xad}, "0xdead"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, describePayload(testCase.payload))
+		})
+	}
+}
+
+func TestVerifyProbeResponse(t *testing.T) {
+	testCases := []struct {
+		name          string
+		probe         options.Probe
+		response      []byte
+		expectedError string
+	}{
+		{"no expectation", options.Probe{}, []byte("anything"), ""},
+		{"matching regex", options.Probe{Expect: `^\+PONG`}, []byte("+PONG\r\n"), ""},
+		{"mismatching regex", options.Probe{Expect: `^\+PONG`}, []byte("-ERR\r\n"), `response "-ERR\r\n" doesn't match ^\+PONG`},
+		{"contained bytes", options.Probe{ExpectBytes: []byte{0x01, 0x02}}, []byte{0x00, 0x01, 0x02, 0x03}, ""},
+		{"missing bytes", options.Probe{ExpectBytes: []byte{0x02, 0x01}}, []byte{0x00, 0x01, 0x02}, "response 0x000102 doesn't contain 0201"},
+		{"both", options.Probe{Expect: "^OK", ExpectBytes: []byte("\n")}, []byte("OK"), `response "OK" doesn't contain 0a`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			expect, err := compileProbeExpect(testCase.probe)
+			assert.NoError(t, err)
+			err = verifyProbeResponse(testCase.probe, expect, testCase.response)
+			if testCase.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, testCase.expectedError)
+			}
+		})
+	}
+}
+
+// startTcpServerForTest serves every connection with the given handler until the test ends, closing the connection
+// once the handler returns, and returns its address.
+func startTcpServerForTest(t *testing.T, handler func(conn net.Conn)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		assert.FailNow(t, "Failed to start TCP server: %s", err.Error())
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() {
+					_ = conn.Close()
+				}()
+				handler(conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
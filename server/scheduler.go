@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// startScheduler runs every configured check on its own interval in the background until ctx is canceled, recording
+// each result in the server state. Checks are independent of each other, so there is no short-circuiting. The returned
+// WaitGroup completes once every check loop has exited, which includes waiting for the runs in progress.
+func startScheduler(ctx context.Context, opts *options.Options, state *serverState) *sync.WaitGroup {
+	var waitGroup sync.WaitGroup
+
+	for _, c := range buildChecks(opts) {
+		waitGroup.Add(1)
+		go func(c check) {
+			defer waitGroup.Done()
+
+			opts.Logger.Infof("Scheduling %s (%s) every %v", c.description, c.name, c.interval)
+
+			// Spread the first runs out, so that checks sharing an interval don't all fire at once
+			delay := jitter(opts)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(delay):
+				}
+
+				// A run that has started is allowed to complete within its timeout when the scheduler is stopped, so
+				// that shutting down doesn't kill scripts halfway through
+				result := runCheck(context.WithoutCancel(ctx), c, opts)
+				if ctx.Err() != nil {
+					return
+				}
+				state.record(c, result, time.Now())
+
+				delay = c.interval + jitter(opts)
+			}
+		}(c)
+	}
+
+	return &waitGroup
+}
+
+// jitter returns a random delay between 0 and the configured --check-jitter
+func jitter(opts *options.Options) time.Duration {
+	if opts.CheckJitter <= 0 {
+		return 0
+	}
+	/* #nosec G404 */
+	return rand.N(time.Duration(opts.CheckJitter) * time.Second)
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/gruntwork-io/health-checker/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackgroundScheduler(t *testing.T) {
+	ports, err := test.GetFreePorts(2)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+
+	// Only listen on the first port, so that the check against the second one fails
+	l, err := net.Listen("tcp", test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0]))
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	go handleRequests(t, l, nil)
+	defer closeListeners(t, []net.Listener{l})
+
+	opts := createOptionsForTest(t, 5, nil, nil, test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, 0), nil)
+	opts.Background = true
+	opts.DetailedStatus = true
+	opts.CheckInterval = 1
+	opts.Ports = []options.PortCheck{
+		{CheckOptions: options.CheckOptions{CheckName: "app"}, Address: fmt.Sprintf("%d", ports[0])},
+		{CheckOptions: options.CheckOptions{CheckName: "down"}, Address: fmt.Sprintf("%d", ports[1])},
+	}
+	liveness := options.Endpoint{Path: "/livez", Checks: []string{"app"}}
+
+	state := newServerState()
+	handler := endpointHandler(opts, liveness, state)
+
+	// Before the first run, the checks are pending and therefore failing
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, recorder.Code)
+
+	var detailed DetailedResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &detailed)
+	assert.NoError(t, err)
+	if assert.Len(t, detailed.Checks, 1) {
+		assert.Equal(t, CheckStatusPending, detailed.Checks[0].Status)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waitGroup := startScheduler(ctx, opts, state)
+	defer func() {
+		cancel()
+		waitGroup.Wait()
+	}()
+
+	assert.Eventually(t, func() bool {
+		_, appOk := state.latest("app")
+		_, downOk := state.latest("down")
+		return appOk && downOk
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The endpoint only runs the passing check, and is answered from the stored results with their age
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	err = json.Unmarshal(recorder.Body.Bytes(), &detailed)
+	assert.NoError(t, err)
+	if assert.Len(t, detailed.Checks, 1) {
+		assert.Equal(t, CheckStatusPassing, detailed.Checks[0].Status)
+		assert.NotEmpty(t, detailed.Checks[0].Age)
+	}
+
+	endpoint := options.Endpoint{Path: "/"}
+	response := buildResponse(opts, endpoint, cachedChecks(opts, endpoint, state), opts.DetailedStatus, MaintenanceStatus{})
+	assert.Equal(t, http.StatusGatewayTimeout, response.StatusCode)
+
+	// Stopping the scheduler must stop every check loop
+	cancel()
+	waitGroup.Wait()
+}
+
+func TestJitter(t *testing.T) {
+	opts := &options.Options{}
+	assert.Equal(t, time.Duration(0), jitter(opts))
+
+	opts.CheckJitter = 2
+	for i := 0; i < 100; i++ {
+		delay := jitter(opts)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.Less(t, delay, 2*time.Second)
+	}
+}
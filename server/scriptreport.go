@@ -0,0 +1,124 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The statuses a script in json mode, and each of its sub-checks, can report. They follow the Nagios plugin states.
+const (
+	scriptStatusOk       = "ok"
+	scriptStatusWarning  = "warning"
+	scriptStatusCritical = "critical"
+	scriptStatusUnknown  = "unknown"
+)
+
+var scriptStatusStates = map[string]int{
+	scriptStatusOk:       nagiosOk,
+	scriptStatusWarning:  nagiosWarning,
+	scriptStatusCritical: nagiosCritical,
+	scriptStatusUnknown:  nagiosUnknown,
+}
+
+// scriptReport is the document a script in json mode prints on stdout. Metrics have the same shape as in the detailed
+// output. If Status is empty, it is the worst status of the sub-checks.
+type scriptReport struct {
+	Status  string           `json:"status"`
+	Message string           `json:"message"`
+	Metrics []Metric         `json:"metrics"`
+	Checks  []scriptSubCheck `json:"checks"`
+}
+
+type scriptSubCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// SubCheckResult is the outcome of a sub-check reported by a script in json mode, such as a single replica checked by
+// a replication lag script.
+type SubCheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// jsonOutcome interprets the stdout and exit error of a script run in json mode. As long as the script printed a valid
+// report, the report alone decides the outcome and the exit status is ignored. Otherwise the check fails, with the
+// error of the script if it didn't exit cleanly.
+func jsonOutcome(stdout string, err error) checkOutcome {
+	report, parseErr := parseScriptReport(stdout)
+	if parseErr != nil {
+		if err == nil {
+			err = parseErr
+		}
+		return checkOutcome{output: stdout, err: err}
+	}
+
+	outcome := checkOutcome{output: report.Message, metrics: report.Metrics}
+	state := scriptStatusStates[report.Status]
+	for _, subCheck := range report.Checks {
+		subState := scriptStatusStates[subCheck.Status]
+		outcome.checks = append(outcome.checks, SubCheckResult{
+			Name:    subCheck.Name,
+			Status:  checkStatusOfState(subState),
+			Message: subCheck.Message,
+		})
+		if report.Status == "" && subState > state {
+			state = subState
+		}
+	}
+
+	outcome.err = nagiosStateError(state, report.Message)
+	outcome.warning = state == nagiosWarning
+	return outcome
+}
+
+// parseScriptReport decodes and validates the report printed by a script in json mode. Unknown fields are ignored, so
+// that scripts can add fields for other consumers.
+func parseScriptReport(stdout string) (scriptReport, error) {
+	var report scriptReport
+	if strings.TrimSpace(stdout) == "" {
+		return report, errors.New("script printed no JSON report")
+	}
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		return report, fmt.Errorf("script printed an invalid JSON report: %w", err)
+	}
+
+	report.Status = strings.ToLower(report.Status)
+	if report.Status == "" && len(report.Checks) == 0 {
+		return report, errors.New("JSON report has neither a status nor any checks")
+	}
+	if _, ok := scriptStatusStates[report.Status]; report.Status != "" && !ok {
+		return report, fmt.Errorf("JSON report has unknown status %q, must be one of: %s, %s, %s, %s", report.Status, scriptStatusOk, scriptStatusWarning, scriptStatusCritical, scriptStatusUnknown)
+	}
+	for i, metric := range report.Metrics {
+		if metric.Name == "" {
+			return report, fmt.Errorf("JSON report metric #%d is missing a name", i+1)
+		}
+	}
+	for i, subCheck := range report.Checks {
+		if subCheck.Name == "" {
+			return report, fmt.Errorf("JSON report check #%d is missing a name", i+1)
+		}
+		report.Checks[i].Status = strings.ToLower(subCheck.Status)
+		if _, ok := scriptStatusStates[report.Checks[i].Status]; !ok {
+			return report, fmt.Errorf("JSON report check %q has unknown status %q", subCheck.Name, subCheck.Status)
+		}
+	}
+	return report, nil
+}
+
+// checkStatusOfState returns the check status reported for a Nagios plugin state.
+func checkStatusOfState(state int) string {
+	switch state {
+	case nagiosOk:
+		return CheckStatusPassing
+	case nagiosWarning:
+		return CheckStatusWarning
+	default:
+		return CheckStatusFailing
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonOutcome(t *testing.T) {
+	exitErr := errors.New("exit status 1")
+
+	testCases := []struct {
+		name            string
+		stdout          string
+		err             error
+		expectedError   string
+		expectedWarning bool
+		expectedOutput  string
+		expectedMetrics []Metric
+		expectedChecks  []SubCheckResult
+	}{
+		{
+			"ok with metrics",
+			`{"status": "ok", "message": "lag is 3s", "metrics": [{"name": "lag", "value": 3.2, "unit": "s", "warning": "60", "critical": "300"}]}`,
+			nil,
+			"",
+			false,
+			"lag is 3s",
+			[]Metric{{Name: "lag", Value: 3.2, Unit: "s", Warning: "60", Critical: "300"}},
+			nil,
+		},
+		{"warning", `{"status": "WARNING", "message": "lag is 90s"}`, nil, "WARNING: lag is 90s", true, "lag is 90s", nil, nil},
+		{"critical without message", `{"status": "critical"}`, nil, "CRITICAL", false, "", nil, nil},
+		{"unknown", `{"status": "unknown", "message": "no replicas"}`, nil, "UNKNOWN: no replicas", false, "no replicas", nil, nil},
+		{"exit status is ignored for a valid report", `{"status": "ok"}`, exitErr, "", false, "", nil, nil},
+		{
+			"status derived from the worst sub-check",
+			`{"checks": [{"name": "replica-1", "status": "ok"}, {"name": "replica-2", "status": "warning", "message": "lag is 90s"}]}`,
+			nil,
+			"WARNING",
+			true,
+			"",
+			nil,
+			[]SubCheckResult{{Name: "replica-1", Status: CheckStatusPassing}, {Name: "replica-2", Status: CheckStatusWarning, Message: "lag is 90s"}},
+		},
+		{
+			"explicit status wins over sub-checks",
+			`{"status": "ok", "message": "quorum", "checks": [{"name": "replica-1", "status": "critical"}]}`,
+			nil,
+			"",
+			false,
+			"quorum",
+			nil,
+			[]SubCheckResult{{Name: "replica-1", Status: CheckStatusFailing}},
+		},
+		{"unknown fields are ignored", `{"status": "ok", "version": 2}`, nil, "", false, "", nil, nil},
+		{"no report", "", nil, "script printed no JSON report", false, "", nil, nil},
+		{"no report after a crash", "", exitErr, "exit status 1", false, "", nil, nil},
+		{"invalid JSON", "OK", nil, "script printed an invalid JSON report: invalid character 'O' looking for beginning of value", false, "OK", nil, nil},
+		{"neither status nor checks", `{"message": "hi"}`, nil, "JSON report has neither a status nor any checks", false, `{"message": "hi"}`, nil, nil},
+		{"unknown status", `{"status": "fine"}`, nil, `JSON report has unknown status "fine", must be one of: ok, warning, critical, unknown`, false, `{"status": "fine"}`, nil, nil},
+		{"metric without name", `{"status": "ok", "metrics": [{"value": 1}]}`, nil, "JSON report metric #1 is missing a name", false, `{"status": "ok", "metrics": [{"value": 1}]}`, nil, nil},
+		{"sub-check with unknown status", `{"checks": [{"name": "a", "status": "meh"}]}`, nil, `JSON report check "a" has unknown status "meh"`, false, `{"checks": [{"name": "a", "status": "meh"}]}`, nil, nil},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			outcome := jsonOutcome(testCase.stdout, testCase.err)
+			if testCase.expectedError == "" {
+				assert.NoError(t, outcome.err)
+			} else {
+				assert.EqualError(t, outcome.err, testCase.expectedError)
+			}
+			assert.Equal(t, testCase.expectedWarning, outcome.warning)
+			assert.Equal(t, testCase.expectedOutput, outcome.output)
+			assert.Equal(t, testCase.expectedMetrics, outcome.metrics)
+			assert.Equal(t, testCase.expectedChecks, outcome.checks)
+		})
+	}
+}
+
+func TestJsonScriptCheck(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Uses a POSIX shell script")
+	}
+
+	script := createDummyScript(t, t.TempDir(), "replication_lag", `#!/bin/sh
+echo 'connecting to replicas' >&2
+cat <<'JSON'
+{"status": "warning", "message": "replica-2 lags behind", "metrics": [{"name": "max_lag", "value": 90, "unit": "s"}], "checks": [{"name": "replica-2", "status": "warning", "message": "lag is 90s"}]}
+JSON
+exit 1
+`)
+	opts := createOptionsForTest(t, 5, []string{script}, nil, "", nil)
+	opts.Scripts[0].Mode = options.ScriptModeJson
+
+	state := newServerState()
+	pass := runChecks(opts, options.Endpoint{Path: "/"}, state)
+	assert.Len(t, pass.results, 1)
+	result := pass.results[0]
+	assert.Equal(t, CheckStatusWarning, result.Status)
+	assert.Equal(t, "WARNING: replica-2 lags behind", result.Error)
+	assert.Equal(t, "replica-2 lags behind", result.Output)
+	assert.Equal(t, []Metric{{Name: "max_lag", Value: 90, Unit: "s"}}, result.Metrics)
+	assert.Equal(t, []SubCheckResult{{Name: "replica-2", Status: CheckStatusWarning, Message: "lag is 90s"}}, result.Checks)
+
+	resp := buildResponse(opts, options.Endpoint{Path: "/"}, pass, true, MaintenanceStatus{})
+	assert.Contains(t, resp.Body, `"checks":[{"name":"replica-2","status":"warning","message":"lag is 90s"}]`)
+	assert.Contains(t, resp.Body, `"metrics":[{"name":"max_lag","value":90,"unit":"s"}]`)
+}
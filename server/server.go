@@ -2,14 +2,12 @@ package server
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
-	"os/exec"
+	"net/http/httptrace"
 	"regexp"
 	"strings"
 	"sync"
@@ -27,25 +25,54 @@ type httpResponse struct {
 }
 
 // DetailedResponse represents a detailed health check response.
-// It includes the status of the health check, the elapsed time, and any errors that occurred.
+// It includes the status of the health check, the elapsed time, any errors that occurred,
+// and the individual result of every configured check.
 type DetailedResponse struct {
-	Status      string   `json:"status"`
-	ElapsedTime string   `json:"elapsed_time"`
-	Errors      []string `json:"errors,omitempty"`
+	Status      string            `json:"status"`
+	ElapsedTime string            `json:"elapsed_time"`
+	Errors      []string          `json:"errors,omitempty"`
+	Checks      []CheckResult     `json:"checks"`
+	Maintenance MaintenanceStatus `json:"maintenance"`
 }
 
-// StartHttpServer starts the health-check HTTP server.
+// StartHttpServer starts the health-check HTTP server, or HTTPS server if a TLS certificate is configured.
 // It leverages strict connection timeouts (Read, Write, Idle) to prevent resource exhaustion attacks
 // such as Slowloris, keeping the health checker resilient under degraded network conditions.
 func StartHttpServer(opts *options.Options) error {
+	return StartHttpServerWithContext(context.Background(), opts)
+}
+
+// StartHttpServerWithContext is StartHttpServer, but shuts the server down gracefully once ctx is canceled: readiness
+// endpoints start failing for the configured drain period so that load balancers deregister the instance, then the
+// listener is closed and in-flight check passes are allowed to complete. It returns nil after a graceful shutdown.
+func StartHttpServerWithContext(ctx context.Context, opts *options.Options) error {
+	state := newServerState()
+
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", httpHandler(opts))
+	for _, endpoint := range opts.EffectiveEndpoints() {
+		mux.HandleFunc(endpoint.Path, endpointHandler(opts, endpoint, state))
+	}
+	if opts.Metrics {
+		mux.HandleFunc(options.MetricsPath, metricsHandler(opts, state))
+	}
+	if opts.Admin {
+		mux.HandleFunc(options.AdminMaintenancePath, adminMaintenanceHandler(opts, state))
+	}
+	watchMaintenanceSignal(ctx, opts, state)
+
+	// In background mode, the checks run on their own schedule for as long as the server is up
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	var scheduler *sync.WaitGroup
+	if opts.Background {
+		scheduler = startScheduler(schedulerCtx, opts, state)
+	}
 
 	// Resolve dynamic default for WriteTimeout if not explicitly provided
-	// Must allow the scripts to run, plus buffer for generating response
+	// Must allow the slowest check to run, plus buffer for generating response
 	writeTimeout := time.Duration(opts.HttpWriteTimeout) * time.Second
 	if writeTimeout == 0 {
-		writeTimeout = time.Duration(opts.ScriptTimeout+5) * time.Second
+		writeTimeout = maxCheckTimeout(opts) + 5*time.Second
 	}
 
 	readTimeout := time.Duration(opts.HttpReadTimeout) * time.Second
@@ -66,176 +93,247 @@ func StartHttpServer(opts *options.Options) error {
 		IdleTimeout:  idleTimeout,
 	}
 
-	err := srv.ListenAndServe()
-	if err != nil {
+	if opts.TLSCert != "" {
+		reloader, err := newTlsReloader(opts)
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = reloader.tlsConfig()
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if opts.TLSCert != "" {
+			// The certificate is served by the TLS config, so that it can be reloaded
+			serveErr <- srv.ListenAndServeTLS("", "")
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
 		return err
+	case <-ctx.Done():
+	}
+
+	shutdown(opts, srv, state, writeTimeout)
+
+	stopScheduler()
+	if scheduler != nil {
+		scheduler.Wait()
 	}
+	opts.Logger.Infof("Shutdown complete.")
 
 	return nil
 }
 
-// httpHandler processes inbound HTTP requests to the health-check endpoint.
-// It acts as the routing logic between Singleflight execution (collapsed concurrent requests)
-// and standard execution.
+// shutdown drains and then stops the server: readiness endpoints fail for the configured drain period while the server
+// keeps serving, after which the listener is closed and in-flight requests get up to timeout to complete.
+func shutdown(opts *options.Options, srv *http.Server, state *serverState, timeout time.Duration) {
+	logger := opts.Logger
+
+	drainPeriod := time.Duration(opts.DrainPeriod) * time.Second
+	logger.Infof("Shutting down. Failing readiness endpoints for %v so that load balancers deregister this instance...", drainPeriod)
+	state.startDraining()
+	// Make clients open new connections, so that they notice once the listener is closed
+	srv.SetKeepAlivesEnabled(false)
+	time.Sleep(drainPeriod)
+
+	logger.Infof("Closing the listener and waiting up to %v for in-flight health checks to complete...", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := srv.Shutdown(shutdownCtx)
+	if err != nil {
+		logger.Warnf("In-flight health checks did not complete in time, closing their connections: %v", err)
+		_ = srv.Close()
+	}
+}
+
+// httpHandler processes inbound HTTP requests to the default health-check endpoint.
 func httpHandler(opts *options.Options) http.HandlerFunc {
+	return endpointHandler(opts, opts.EffectiveEndpoints()[0], newServerState())
+}
+
+// endpointHandler processes inbound HTTP requests to a single health-check endpoint.
+// It acts as the routing logic between background mode (answering from the latest stored results),
+// Singleflight execution (collapsed concurrent requests) and standard execution. The detailed JSON
+// response is only returned to authorized requests, everybody else gets the plain status.
+func endpointHandler(opts *options.Options, endpoint options.Endpoint, state *serverState) http.HandlerFunc {
 	var group singleflight.Group
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		var resp *httpResponse
+		var pass checkPass
 		logger := opts.Logger
 
+		detailed := opts.DetailedStatus && authorized(r, opts.Auth)
+		if opts.DetailedStatus && !detailed {
+			logger.Debugf("Request from %s is not authorized to see the detailed status. Returning the plain status.", r.RemoteAddr)
+		}
+
+		if state.isDraining() && endpoint.GatesTraffic() {
+			logger.Infof("Received inbound request while shutting down. Returning HTTP %d response.", endpoint.EffectiveFailureStatusCode())
+			respond(w, opts, endpoint, state, drainingResponse(endpoint, detailed))
+			return
+		}
+
+		// In Background mode the checks are run by the scheduler, so the
+		// request is answered instantly from the latest stored results
 		// In Singleflight mode only one runChecks pass will be performed
 		// at any given time, with the result being shared across concurrent
 		// inbound requests
-		if opts.Singleflight {
+		if opts.Background {
+			logger.Debugf("Received inbound request. Answering from the latest background results...")
+			pass = cachedChecks(opts, endpoint, state)
+		} else if opts.Singleflight {
 			logger.Infof("Received inbound request. Performing singleflight health checks...")
 
 			result, _, shared := group.Do("check", func() (interface{}, error) {
 				logger.Infof("Beginning health checks...")
-				return runChecks(opts), nil
+				return runChecks(opts, endpoint, state), nil
 			})
 
 			if shared {
 				logger.Infof("Singleflight health check response was shared between multiple requests.")
+				state.metrics.countSharedResult(endpoint.Path)
 			}
 
-			resp = result.(*httpResponse)
+			pass = result.(checkPass)
 		} else {
 			logger.Infof("Received inbound request. Beginning health checks...")
-			resp = runChecks(opts)
+			pass = runChecks(opts, endpoint, state)
 		}
 
-		err := writeHttpResponse(w, resp)
-		if err != nil {
-			opts.Logger.Error("Failed to send HTTP response. Exiting.")
-			panic(err)
-		}
+		respond(w, opts, endpoint, state, buildResponse(opts, endpoint, pass, detailed, state.maintenanceStatus(opts)))
 	}
 }
 
-// runChecks performs all configured health checks (TCP ports and scripts) in parallel using goroutines.
+// respond writes the response of an endpoint and counts it in the metrics.
+func respond(w http.ResponseWriter, opts *options.Options, endpoint options.Endpoint, state *serverState, resp *httpResponse) {
+	state.metrics.countRequest(endpoint.Path, resp.StatusCode)
+
+	err := writeHttpResponse(w, resp)
+	if err != nil {
+		opts.Logger.Error("Failed to send HTTP response. Exiting.")
+		panic(err)
+	}
+}
+
+// checkPass holds the per-check results for an endpoint and how long it took to get them.
+type checkPass struct {
+	results []CheckResult
+	elapsed time.Duration
+}
+
+// runChecks performs all configured health checks (TCP ports, scripts and HTTP checks) in parallel using goroutines.
 // It leverages early short-circuiting: a master cancellation context ensures that if any single probe fails,
 // all other actively running probes are immediately aborted to return a swift 504 error to the load balancer
-// without waiting for maximum timeouts to be reached.
-func runChecks(opts *options.Options) *httpResponse {
-	logger := opts.Logger
-
+// without waiting for maximum timeouts to be reached. Only the checks assigned to the endpoint are run. If the
+// endpoint is in run-all mode, short-circuiting is disabled and every check runs to completion so that all failures
+// are reported. Every check is reported by name, including the ones that passed or were canceled, with its status
+// damped by the check's consecutive failure and success thresholds.
+func runChecks(opts *options.Options, endpoint options.Endpoint, state *serverState) checkPass {
 	startTime := time.Now()
+	results := executeChecks(opts, endpoint, state)
+	return checkPass{results: results, elapsed: time.Since(startTime)}
+}
 
-	var errorMessages []string
-	var errorMu sync.Mutex
+// executeChecks runs the checks of the given endpoint in parallel and returns their results, in the same order as
+// endpointChecks.
+func executeChecks(opts *options.Options, endpoint options.Endpoint, state *serverState) []CheckResult {
+	checks := endpointChecks(opts, endpoint)
+	results := make([]CheckResult, len(checks))
 
 	var waitGroup = sync.WaitGroup{}
 
 	// Create a master context that can be canceled
-	// If detailed status is not requested, the first error will trigger cancellation
+	// Unless in run-all mode, the first failing critical check will trigger cancellation of all the others
 	masterCtx, masterCancel := context.WithCancel(context.Background())
 	defer masterCancel()
 
-	for _, port := range opts.Ports {
+	for i, c := range checks {
 		waitGroup.Add(1)
-		go func(portStr string) {
+		go func(i int, c check) {
 			defer waitGroup.Done()
 
-			err := attemptTcpConnection(masterCtx, portStr, opts)
-			if err != nil {
-				// Don't report context cancelation as an explicit "failure" to avoid noise
-				if errors.Is(err, context.Canceled) {
-					return
-				}
-
-				logger.Warnf("TCP connection to %s FAILED: %s", portStr, err)
-				errorMu.Lock()
-				errorMessages = append(errorMessages, fmt.Sprintf("TCP connection to %s failed: %s", portStr, err.Error()))
-				errorMu.Unlock()
-
+			result := state.record(c, runCheck(masterCtx, c, opts), time.Now())
+			if result.Status == CheckStatusFailing && result.Severity == options.SeverityCritical && !endpoint.RunAll {
 				masterCancel()
-			} else {
-				logger.Infof("TCP connection to %s successful", portStr)
 			}
-		}(port)
+			results[i] = result
+		}(i, c)
 	}
 
-	for _, script := range opts.Scripts {
-		waitGroup.Add(1)
-		go func(script options.Script) {
-			defer waitGroup.Done()
-
-			logger.Infof("Executing '%v' with a timeout of %v seconds...", script, opts.ScriptTimeout)
-
-			timeout := time.Second * time.Duration(opts.ScriptTimeout)
-
-			// Use the masterCtx as the parent so that if it is canceled, the script terminates immediately
-			ctx, cancel := context.WithTimeout(masterCtx, timeout)
-			defer cancel()
+	waitGroup.Wait()
 
-			/* #nosec G204 */
-			cmd := exec.CommandContext(ctx, script.Name, script.Args...)
-			output, err := cmd.CombinedOutput()
+	return results
+}
 
-			if err != nil {
-				// Avoid logging context cancellation errors from short-circuiting
-				if masterCtx.Err() != nil {
-					return
-				}
+// cachedChecks answers for the given endpoint from the latest results stored by the background scheduler, without
+// running any check. Checks that have not completed their first run yet are reported as pending and count as failed.
+func cachedChecks(opts *options.Options, endpoint options.Endpoint, state *serverState) checkPass {
+	startTime := time.Now()
 
-				logger.Warnf("Script %v FAILED: %s", script.Name, err)
-				logger.Warnf("Command output: %s", output)
-				errorMu.Lock()
-				errorMessages = append(errorMessages, fmt.Sprintf("Script %v failed: %s (Output: %s)", script.Name, err.Error(), string(output)))
-				errorMu.Unlock()
+	checks := endpointChecks(opts, endpoint)
+	results := make([]CheckResult, 0, len(checks))
+
+	for _, c := range checks {
+		stored, ok := state.latest(c.name)
+		if !ok {
+			results = append(results, CheckResult{
+				Name:     c.name,
+				Type:     c.kind,
+				Severity: c.severity,
+				Status:   CheckStatusPending,
+				Error:    "check has not completed yet",
+				message:  fmt.Sprintf("%s has not completed yet", c.description),
+			})
+			continue
+		}
 
-				masterCancel()
-			} else {
-				logger.Infof("Script %v successful", script)
-			}
-		}(script)
+		result := stored.result
+		result.Age = time.Since(stored.checkedAt).Round(time.Millisecond).String()
+		results = append(results, result)
 	}
 
-	for _, httpCheck := range opts.HttpChecks {
-		waitGroup.Add(1)
-		go func(httpCheck options.HttpCheck) {
-			defer waitGroup.Done()
-
-			err := attemptHttpConnection(masterCtx, httpCheck, opts)
-			if err != nil {
-				if errors.Is(err, context.Canceled) {
-					return
-				}
-
-				logger.Warnf("HTTP check to %s FAILED: %s", httpCheck.Url, err)
-				errorMu.Lock()
-				errorMessages = append(errorMessages, fmt.Sprintf("HTTP check to %s failed: %s", httpCheck.Url, err.Error()))
-				errorMu.Unlock()
-
-				masterCancel()
-			} else {
-				logger.Infof("HTTP check to %s successful", httpCheck.Url)
-			}
-		}(httpCheck)
-	}
+	return checkPass{results: results, elapsed: time.Since(startTime)}
+}
 
-	waitGroup.Wait()
+// buildResponse turns the per-check results for an endpoint into the HTTP response, with a plain text or, if detailed
+// is set, detailed JSON body: 200 if no check failed, the endpoint's failure status code if a critical check failed, and
+// its degraded status code if only warning checks failed. In maintenance mode, endpoints that gate traffic return the
+// maintenance status code and message instead, whatever the outcome of the checks.
+func buildResponse(opts *options.Options, endpoint options.Endpoint, pass checkPass, detailed bool, maintenance MaintenanceStatus) *httpResponse {
+	logger := opts.Logger
 
-	elapsedTime := time.Since(startTime).String()
+	failed, degraded, errorMessages := summarize(pass.results)
+	statusText := summaryText(failed, degraded)
 
 	statusCode := http.StatusOK
-	statusText := "OK"
-	body := "OK"
 	contentType := "text/plain"
 
-	if len(errorMessages) > 0 {
-		statusCode = http.StatusGatewayTimeout
-		statusText = "At least one health check failed"
-		body = statusText
+	if failed {
+		statusCode = endpoint.EffectiveFailureStatusCode()
+	} else if degraded {
+		statusCode = endpoint.EffectiveDegradedStatusCode(opts)
 	}
 
-	if opts.DetailedStatus {
+	inMaintenance := maintenance.Enabled && endpoint.GatesTraffic()
+	if inMaintenance {
+		statusCode = opts.Maintenance.StatusCode
+		statusText = opts.Maintenance.Message
+	}
+	body := statusText
+
+	if detailed {
 		contentType = "application/json"
 		detailedResp := DetailedResponse{
 			Status:      statusText,
-			ElapsedTime: elapsedTime,
+			ElapsedTime: pass.elapsed.String(),
 			Errors:      errorMessages,
+			Checks:      pass.results,
+			Maintenance: maintenance,
 		}
 		jsonBytes, err := json.Marshal(detailedResp)
 		if err == nil {
@@ -246,69 +344,216 @@ func runChecks(opts *options.Options) *httpResponse {
 		}
 	}
 
-	if statusCode == http.StatusOK {
-		logger.Infof("All health checks passed. Returning HTTP 200 response.")
+	if inMaintenance {
+		logger.Infof("In maintenance mode. Returning HTTP %d response.", statusCode)
+	} else if failed {
+		logger.Infof("At least one health check failed. Returning HTTP %d response.", statusCode)
+	} else if degraded {
+		logger.Infof("At least one non-critical health check failed. Returning HTTP %d response.", statusCode)
 	} else {
-		logger.Infof("At least one health check failed. Returning HTTP 504 response.")
+		logger.Infof("All health checks passed. Returning HTTP 200 response.")
 	}
 
 	return &httpResponse{StatusCode: statusCode, Body: body, ContentType: contentType}
 }
 
-// Attempt to open a TCP connection to the given address (can be port only or host:port)
-func attemptTcpConnection(ctx context.Context, portStr string, opts *options.Options) error {
-	logger := opts.Logger
-	logger.Infof("Attempting to connect to %s via TCP...", portStr)
+// drainingResponse is returned without running any check by the endpoints that fail while the server drains before
+// shutting down.
+func drainingResponse(endpoint options.Endpoint, detailed bool) *httpResponse {
+	statusText := "draining"
+	if !detailed {
+		return &httpResponse{StatusCode: endpoint.EffectiveFailureStatusCode(), Body: statusText, ContentType: "text/plain"}
+	}
+
+	jsonBytes, _ := json.Marshal(DetailedResponse{Status: statusText, ElapsedTime: time.Duration(0).String(), Checks: []CheckResult{}})
+	return &httpResponse{StatusCode: endpoint.EffectiveFailureStatusCode(), Body: string(jsonBytes), ContentType: "application/json"}
+}
+
+// summarize reports whether any critical check failed, whether any warning check failed or any non-informational check
+// reported a warning, and the error messages of all failing, warning or pending checks. Failing informational checks
+// are reported, but don't affect the outcome.
+func summarize(results []CheckResult) (failed bool, degraded bool, errorMessages []string) {
+	for _, result := range results {
+		if result.Status == CheckStatusWarning {
+			errorMessages = append(errorMessages, result.message)
+			if result.Severity != options.SeverityInfo {
+				degraded = true
+			}
+			continue
+		}
+		if result.Status == CheckStatusFailing || result.Status == CheckStatusPending {
+			errorMessages = append(errorMessages, result.message)
+
+			switch result.Severity {
+			case options.SeverityCritical:
+				failed = true
+			case options.SeverityWarning:
+				degraded = true
+			}
+		}
+	}
+	return failed, degraded, errorMessages
+}
 
-	defaultTimeout := time.Duration(opts.TcpDialTimeout) * time.Second
-	if defaultTimeout == 0 {
-		defaultTimeout = time.Second * 5
+// summaryText returns the status reported in the response body for the given outcome.
+func summaryText(failed bool, degraded bool) string {
+	switch {
+	case failed:
+		return "At least one health check failed"
+	case degraded:
+		return "degraded"
+	default:
+		return "OK"
 	}
+}
 
-	dialer := net.Dialer{Timeout: defaultTimeout}
+// scriptTimeout returns the script's own timeout if it has one, otherwise the global --script-timeout
+func scriptTimeout(script options.Script, opts *options.Options) time.Duration {
+	if script.Timeout > 0 {
+		return time.Duration(script.Timeout) * time.Second
+	}
+	return time.Duration(opts.ScriptTimeout) * time.Second
+}
+
+// tcpTimeout returns the port check's own timeout if it has one, otherwise the global --tcp-dial-timeout
+func tcpTimeout(port options.PortCheck, opts *options.Options) time.Duration {
+	if port.Timeout > 0 {
+		return time.Duration(port.Timeout) * time.Second
+	}
+	if opts.TcpDialTimeout > 0 {
+		return time.Duration(opts.TcpDialTimeout) * time.Second
+	}
+	return time.Second * 5
+}
+
+// httpTimeout returns the HTTP check's own timeout if it has one, otherwise the global --http-dial-timeout
+func httpTimeout(httpCheck options.HttpCheck, opts *options.Options) time.Duration {
+	if httpCheck.Timeout > 0 {
+		return time.Duration(httpCheck.Timeout) * time.Second
+	}
+	if opts.HttpDialTimeout > 0 {
+		return time.Duration(opts.HttpDialTimeout) * time.Second
+	}
+	return time.Second * 5
+}
+
+// dnsTimeout returns the DNS check's own timeout if it has one, otherwise 5 seconds
+func dnsTimeout(dnsCheck options.DnsCheck) time.Duration {
+	if dnsCheck.Timeout > 0 {
+		return time.Duration(dnsCheck.Timeout) * time.Second
+	}
+	return time.Second * 5
+}
+
+// tlsTimeout returns the TLS check's own timeout if it has one, otherwise the global --tcp-dial-timeout
+func tlsTimeout(tlsCheck options.TlsCheck, opts *options.Options) time.Duration {
+	if tlsCheck.Timeout > 0 {
+		return time.Duration(tlsCheck.Timeout) * time.Second
+	}
+	if opts.TcpDialTimeout > 0 {
+		return time.Duration(opts.TcpDialTimeout) * time.Second
+	}
+	return time.Second * 5
+}
+
+// udpTimeout returns the UDP check's own timeout if it has one, otherwise 5 seconds
+func udpTimeout(udpCheck options.UdpCheck) time.Duration {
+	if udpCheck.Timeout > 0 {
+		return time.Duration(udpCheck.Timeout) * time.Second
+	}
+	return time.Second * 5
+}
+
+// maxCheckTimeout returns the largest effective timeout across all configured checks, which bounds how long a single
+// runChecks pass can take. Without any checks it falls back to the global script timeout.
+func maxCheckTimeout(opts *options.Options) time.Duration {
+	checks := buildChecks(opts)
+	if len(checks) == 0 {
+		return time.Duration(opts.ScriptTimeout) * time.Second
+	}
+
+	var longest time.Duration
+	for _, c := range checks {
+		longest = max(longest, c.timeout)
+	}
+	return longest
+}
+
+// Attempt to open a TCP connection to the given address (can be port only or host:port), and then exchange the
+// check's probe over it if it has one. The timeout of the check bounds both the dial and the exchange.
+func attemptTcpConnection(ctx context.Context, port options.PortCheck, opts *options.Options) checkOutcome {
+	logger := opts.Logger
+	logger.Infof("Attempting to connect to %s via TCP...", port.Address)
+
+	ctx, cancel := context.WithTimeout(ctx, tcpTimeout(port, opts))
+	defer cancel()
+
+	var dialer net.Dialer
 
 	// If only a port is provided, default to 0.0.0.0
-	address := portStr
+	address := port.Address
 	if !strings.Contains(address, ":") {
-		address = fmt.Sprintf("0.0.0.0:%s", portStr)
+		address = fmt.Sprintf("0.0.0.0:%s", port.Address)
 	}
 
 	conn, err := dialer.DialContext(ctx, "tcp", address)
 	if err != nil {
-		return err
+		return checkOutcome{err: err}
 	}
 
 	defer func() {
 		_ = conn.Close()
 	}()
 
-	return nil
+	return probeTcpConnection(ctx, conn, port, opts)
 }
 
-// Attempt to perform an HTTP(S) GET request and optionally verify the payload
-func attemptHttpConnection(ctx context.Context, httpCheck options.HttpCheck, opts *options.Options) error {
-	logger := opts.Logger
-	logger.Infof("Attempting to perform HTTP check to %s...", httpCheck.Url)
+// Attempt to perform the HTTP(S) request of the check and verify the response and how long it took. The duration of
+// the request and its phases are reported as metrics.
+func attemptHttpConnection(ctx context.Context, httpCheck options.HttpCheck, opts *options.Options) checkOutcome {
+	timings := &httpTimings{}
+	ctx = httptrace.WithClientTrace(ctx, timings.clientTrace())
+
+	start := time.Now()
+	err := performHttpRequest(ctx, httpCheck, opts)
+	elapsed := time.Since(start)
 
-	defaultTimeout := time.Duration(opts.HttpDialTimeout) * time.Second
-	if defaultTimeout == 0 {
-		defaultTimeout = time.Second * 5
+	outcome := checkOutcome{err: err, metrics: timings.metrics(httpCheck, elapsed)}
+	if err != nil {
+		return outcome
+	}
+	switch {
+	case exceedsLatencyBudget(elapsed, httpCheck.LatencyCriticalMs):
+		outcome.err = fmt.Errorf("HTTP check took %v, longer than the critical latency budget of %dms", elapsed.Round(time.Millisecond), httpCheck.LatencyCriticalMs)
+	case exceedsLatencyBudget(elapsed, httpCheck.LatencyWarningMs):
+		outcome.err = fmt.Errorf("HTTP check took %v, longer than the warning latency budget of %dms", elapsed.Round(time.Millisecond), httpCheck.LatencyWarningMs)
+		outcome.warning = true
 	}
+	return outcome
+}
+
+// performHttpRequest performs the HTTP(S) request of the check and verifies the response
+func performHttpRequest(ctx context.Context, httpCheck options.HttpCheck, opts *options.Options) error {
+	logger := opts.Logger
+	logger.Infof("Attempting to perform HTTP check to %s %s...", httpCheck.EffectiveMethod(), httpCheck.Url)
 
 	// Create a new client to avoid sharing state or keeping keep-alives open unnecessarily
 	transport := http.DefaultTransport.(*http.Transport).Clone()
-	if opts.AllowInsecureTLS {
-		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} // #nosec G402
+	tlsConfig, err := httpCheckTlsConfig(httpCheck, opts)
+	if err != nil {
+		return err
 	}
+	transport.TLSClientConfig = tlsConfig
 
 	client := &http.Client{
-		Timeout:   defaultTimeout,
-		Transport: transport,
+		Timeout:       httpTimeout(httpCheck, opts),
+		Transport:     transport,
+		CheckRedirect: httpCheckRedirectPolicy(httpCheck),
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, httpCheck.Url, nil)
+	req, err := newHttpCheckRequest(ctx, httpCheck)
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %w", err)
+		return err
 	}
 
 	/* #nosec G107 G704 */
@@ -322,7 +567,7 @@ func attemptHttpConnection(ctx context.Context, httpCheck options.HttpCheck, opt
 
 	// Discard the body if we don't need it, but always read to allow connection reuse/clean closure
 	var bodyBytes []byte
-	if httpCheck.VerifyPayload != "" {
+	if httpCheck.VerifyPayload != "" || len(httpCheck.JsonAssertions) > 0 {
 		bodyBytes, err = io.ReadAll(resp.Body)
 		if err != nil {
 			return fmt.Errorf("failed to read HTTP response body: %w", err)
@@ -331,8 +576,14 @@ func attemptHttpConnection(ctx context.Context, httpCheck options.HttpCheck, opt
 		_, _ = io.Copy(io.Discard, resp.Body)
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("HTTP check returned non-2xx status code: %d", resp.StatusCode)
+	if err := verifyHttpStatus(httpCheck, resp.StatusCode); err != nil {
+		return err
+	}
+	if err := verifyFinalUrl(httpCheck, resp); err != nil {
+		return err
+	}
+	if err := verifyHeaderAssertions(httpCheck.HeaderAssertions, resp.Header); err != nil {
+		return err
 	}
 
 	if httpCheck.VerifyPayload != "" {
@@ -344,6 +595,11 @@ func attemptHttpConnection(ctx context.Context, httpCheck options.HttpCheck, opt
 			return fmt.Errorf("HTTP response body did not match verify-payload regex '%s'", httpCheck.VerifyPayload)
 		}
 	}
+	if len(httpCheck.JsonAssertions) > 0 {
+		if err := verifyJsonAssertions(httpCheck.JsonAssertions, bodyBytes); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
@@ -14,6 +15,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gruntwork-io/go-commons/logging"
 	"github.com/gruntwork-io/health-checker/options"
@@ -227,12 +229,302 @@ func TestParseChecksFromConfig(t *testing.T) {
 			opts := createOptionsForTest(t, testCase.scriptTimeout, testCase.scripts, testCase.httpChecks, listenerString, checkPorts)
 
 			// Run the checks and verify the status code
-			response := runChecks(opts)
+			response := respondForTest(opts, opts.EffectiveEndpoints()[0], newServerState())
 			assert.True(t, testCase.expectedStatus == response.StatusCode, "Got expected status code")
 		})
 	}
 }
 
+func TestPerCheckTimeouts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Uses a POSIX sleep script")
+	}
+
+	tmpDir := t.TempDir()
+	slowScript := createDummyScript(t, tmpDir, "slow_script", "#!/bin/sh\nsleep 2\n")
+
+	testCases := []struct {
+		name           string
+		checkTimeout   int
+		expectedStatus int
+	}{
+		{"falls back to global timeout", 0, 200},
+		{"own timeout shorter than script", 1, 504},
+		{"own timeout longer than script", 4, 200},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			opts := createOptionsForTest(t, 5, []string{slowScript}, nil, test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, 0), nil)
+			opts.Scripts[0].Timeout = testCase.checkTimeout
+
+			response := respondForTest(opts, opts.EffectiveEndpoints()[0], newServerState())
+			assert.Equal(t, testCase.expectedStatus, response.StatusCode)
+		})
+	}
+}
+
+func TestDetailedStatusReportsEveryCheck(t *testing.T) {
+	ports, err := test.GetFreePorts(2)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+
+	// Only listen on the first port, so that the check against the second one fails
+	l, err := net.Listen("tcp", test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0]))
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	go handleRequests(t, l, nil)
+	defer closeListeners(t, []net.Listener{l})
+
+	opts := createOptionsForTest(t, 5, nil, nil, test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, 0), nil)
+	opts.DetailedStatus = true
+	opts.Ports = []options.PortCheck{
+		{CheckOptions: options.CheckOptions{CheckName: "app"}, Address: fmt.Sprintf("%d", ports[0])},
+		{Address: fmt.Sprintf("%d", ports[1])},
+	}
+
+	response := respondForTest(opts, opts.EffectiveEndpoints()[0], newServerState())
+	assert.Equal(t, http.StatusGatewayTimeout, response.StatusCode)
+	assert.Equal(t, "application/json", response.ContentType)
+
+	var detailed DetailedResponse
+	err = json.Unmarshal([]byte(response.Body), &detailed)
+	assert.NoError(t, err)
+	assert.Len(t, detailed.Errors, 1)
+
+	if assert.Len(t, detailed.Checks, 2) {
+		assert.Equal(t, "app", detailed.Checks[0].Name)
+		assert.Equal(t, options.CheckTypeTcp, detailed.Checks[0].Type)
+		assert.Contains(t, []string{CheckStatusPassing, CheckStatusCanceled}, detailed.Checks[0].Status)
+
+		assert.Equal(t, fmt.Sprintf("%d", ports[1]), detailed.Checks[1].Name, "Unnamed checks are identified by their target")
+		assert.Equal(t, CheckStatusFailing, detailed.Checks[1].Status)
+		assert.NotEmpty(t, detailed.Checks[1].Error)
+		assert.NotEmpty(t, detailed.Checks[1].Duration)
+	}
+}
+
+func TestRunAll(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Uses a POSIX sleep script")
+	}
+
+	tmpDir := t.TempDir()
+	slowFailScript := createDummyScript(t, tmpDir, "slow_fail_script", "#!/bin/sh\nsleep 1\nexit 1\n")
+
+	ports, err := test.GetFreePorts(1)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+
+	testCases := []struct {
+		name             string
+		runAll           bool
+		expectedErrors   int
+		expectedStatuses []string
+	}{
+		{"short-circuit by default", false, 1, []string{CheckStatusFailing, CheckStatusCanceled}},
+		{"run-all reports every failure", true, 2, []string{CheckStatusFailing, CheckStatusFailing}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// Nothing listens on the port, so the TCP check fails immediately while the script is still running
+			opts := createOptionsForTest(t, 5, []string{slowFailScript}, nil, test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, 0), []string{fmt.Sprintf("%d", ports[0])})
+			opts.DetailedStatus = true
+
+			response := respondForTest(opts, options.Endpoint{Path: "/", RunAll: testCase.runAll}, newServerState())
+			assert.Equal(t, http.StatusGatewayTimeout, response.StatusCode)
+
+			var detailed DetailedResponse
+			err := json.Unmarshal([]byte(response.Body), &detailed)
+			assert.NoError(t, err)
+			assert.Len(t, detailed.Errors, testCase.expectedErrors)
+
+			var statuses []string
+			for _, result := range detailed.Checks {
+				statuses = append(statuses, result.Status)
+			}
+			assert.Equal(t, testCase.expectedStatuses, statuses)
+		})
+	}
+}
+
+func TestEndpointCheckSubsets(t *testing.T) {
+	ports, err := test.GetFreePorts(2)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+
+	// Only listen on the first port, so that the "downstream" check against the second one fails
+	l, err := net.Listen("tcp", test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0]))
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	go handleRequests(t, l, nil)
+	defer closeListeners(t, []net.Listener{l})
+
+	opts := createOptionsForTest(t, 5, nil, nil, test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, 0), nil)
+	opts.DetailedStatus = true
+	opts.Ports = []options.PortCheck{
+		{CheckOptions: options.CheckOptions{CheckName: "app"}, Address: fmt.Sprintf("%d", ports[0])},
+		{CheckOptions: options.CheckOptions{CheckName: "downstream"}, Address: fmt.Sprintf("%d", ports[1])},
+	}
+
+	testCases := []struct {
+		name           string
+		endpoint       options.Endpoint
+		expectedStatus int
+		expectedChecks int
+	}{
+		{"liveness ignores downstream", options.Endpoint{Path: "/livez", Checks: []string{"app"}}, http.StatusOK, 1},
+		{"readiness includes downstream", options.Endpoint{Path: "/readyz", Checks: []string{"app", "downstream"}, FailureStatusCode: http.StatusServiceUnavailable}, http.StatusServiceUnavailable, 2},
+		{"all checks by default", options.Endpoint{Path: "/"}, http.StatusGatewayTimeout, 2},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			response := respondForTest(opts, testCase.endpoint, newServerState())
+			assert.Equal(t, testCase.expectedStatus, response.StatusCode)
+
+			var detailed DetailedResponse
+			err := json.Unmarshal([]byte(response.Body), &detailed)
+			assert.NoError(t, err)
+			assert.Len(t, detailed.Checks, testCase.expectedChecks)
+		})
+	}
+}
+
+func TestCheckSeverities(t *testing.T) {
+	ports, err := test.GetFreePorts(3)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+
+	// Only listen on the first port, so that the checks against the other two fail
+	l, err := net.Listen("tcp", test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0]))
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	go handleRequests(t, l, nil)
+	defer closeListeners(t, []net.Listener{l})
+
+	up := fmt.Sprintf("%d", ports[0])
+	down := fmt.Sprintf("%d", ports[1])
+	alsoDown := fmt.Sprintf("%d", ports[2])
+
+	testCases := []struct {
+		name           string
+		ports          []options.PortCheck
+		endpoint       options.Endpoint
+		degradedStatus int
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"failing informational check is ignored",
+			[]options.PortCheck{{Address: up}, {CheckOptions: options.CheckOptions{Severity: options.SeverityInfo}, Address: down}},
+			options.Endpoint{Path: "/"},
+			0,
+			http.StatusOK,
+			"OK",
+		},
+		{
+			"failing warning check degrades",
+			[]options.PortCheck{{Address: up}, {CheckOptions: options.CheckOptions{Severity: options.SeverityWarning}, Address: down}},
+			options.Endpoint{Path: "/"},
+			0,
+			http.StatusOK,
+			"degraded",
+		},
+		{
+			"global degraded status code",
+			[]options.PortCheck{{Address: up}, {CheckOptions: options.CheckOptions{Severity: options.SeverityWarning}, Address: down}},
+			options.Endpoint{Path: "/"},
+			http.StatusMultiStatus,
+			http.StatusMultiStatus,
+			"degraded",
+		},
+		{
+			"endpoint degraded status code",
+			[]options.PortCheck{{Address: up}, {CheckOptions: options.CheckOptions{Severity: options.SeverityWarning}, Address: down}},
+			options.Endpoint{Path: "/", DegradedStatusCode: http.StatusTooManyRequests},
+			http.StatusMultiStatus,
+			http.StatusTooManyRequests,
+			"degraded",
+		},
+		{
+			"failing critical check wins over warning",
+			[]options.PortCheck{{CheckOptions: options.CheckOptions{Severity: options.SeverityWarning}, Address: down}, {Address: alsoDown}},
+			options.Endpoint{Path: "/", RunAll: true},
+			0,
+			http.StatusGatewayTimeout,
+			"At least one health check failed",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			opts := createOptionsForTest(t, 5, nil, nil, test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, 0), nil)
+			opts.Ports = testCase.ports
+			opts.DegradedStatus = testCase.degradedStatus
+
+			response := respondForTest(opts, testCase.endpoint, newServerState())
+			assert.Equal(t, testCase.expectedStatus, response.StatusCode)
+			assert.Equal(t, testCase.expectedBody, response.Body)
+		})
+	}
+}
+
+func TestWarningFailureDoesNotShortCircuit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Uses a POSIX sleep script")
+	}
+
+	tmpDir := t.TempDir()
+	slowScript := createDummyScript(t, tmpDir, "slow_script", "#!/bin/sh\nsleep 1\n")
+
+	ports, err := test.GetFreePorts(1)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+
+	// Nothing listens on the port, so the warning check fails immediately while the script is still running
+	opts := createOptionsForTest(t, 5, []string{slowScript}, nil, test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, 0), nil)
+	opts.DetailedStatus = true
+	opts.Ports = []options.PortCheck{{CheckOptions: options.CheckOptions{Severity: options.SeverityWarning}, Address: fmt.Sprintf("%d", ports[0])}}
+
+	response := respondForTest(opts, options.Endpoint{Path: "/"}, newServerState())
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	var detailed DetailedResponse
+	err = json.Unmarshal([]byte(response.Body), &detailed)
+	assert.NoError(t, err)
+	assert.Equal(t, "degraded", detailed.Status)
+	if assert.Len(t, detailed.Checks, 2) {
+		assert.Equal(t, options.SeverityWarning, detailed.Checks[0].Severity)
+		assert.Equal(t, CheckStatusFailing, detailed.Checks[0].Status)
+		assert.Equal(t, options.SeverityCritical, detailed.Checks[1].Severity)
+		assert.Equal(t, CheckStatusPassing, detailed.Checks[1].Status, "A warning failure must not cancel critical checks")
+	}
+}
+
+func TestMaxCheckTimeout(t *testing.T) {
+	opts := &options.Options{ScriptTimeout: 5, TcpDialTimeout: 3, HttpDialTimeout: 4}
+	assert.Equal(t, 5*time.Second, maxCheckTimeout(opts), "No checks falls back to the script timeout")
+
+	opts.Ports = []options.PortCheck{{Address: "8080"}}
+	assert.Equal(t, 3*time.Second, maxCheckTimeout(opts), "Only the configured check types count")
+
+	opts.HttpChecks = []options.HttpCheck{{CheckOptions: options.CheckOptions{Timeout: 30}, Url: "http://localhost"}}
+	assert.Equal(t, 30*time.Second, maxCheckTimeout(opts), "A per-check timeout raises the maximum")
+
+	opts.Scripts = []options.Script{{CheckOptions: options.CheckOptions{Timeout: 1}, Name: "check.sh"}}
+	assert.Equal(t, 30*time.Second, maxCheckTimeout(opts))
+}
+
 func TestSingleflight(t *testing.T) {
 
 	testCases := []struct {
@@ -319,6 +611,11 @@ func TestSingleflight(t *testing.T) {
 
 }
 
+// respondForTest runs the checks of the given endpoint and builds the response, as an authorized request to it would.
+func respondForTest(opts *options.Options, endpoint options.Endpoint, state *serverState) *httpResponse {
+	return buildResponse(opts, endpoint, runChecks(opts, endpoint, state), opts.DetailedStatus, MaintenanceStatus{})
+}
+
 func closeListeners(t *testing.T, listeners []net.Listener) {
 	for _, l := range listeners {
 		err := l.Close()
@@ -361,6 +658,6 @@ func createOptionsForTest(t *testing.T, scriptTimeout int, scripts []string, htt
 	opts.HttpChecks = httpChecks
 
 	opts.Listener = listener
-	opts.Ports = ports
+	opts.Ports = options.ParsePorts(ports)
 	return opts
 }
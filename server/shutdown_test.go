@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/gruntwork-io/health-checker/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Uses a POSIX sleep script")
+	}
+
+	slowScript := createDummyScript(t, t.TempDir(), "slow_script", "#!/bin/sh\nsleep 5\n")
+
+	ports, err := test.GetFreePorts(2)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+
+	l, err := net.Listen("tcp", test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0]))
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	go handleRequests(t, l, nil)
+	defer closeListeners(t, []net.Listener{l})
+
+	listener := test.ListenerString("127.0.0.1", ports[1])
+	app := fmt.Sprintf("%d", ports[0])
+	opts := createOptionsForTest(t, 10, []string{slowScript}, nil, listener, []string{app})
+	opts.DrainPeriod = 4
+	opts.Endpoints = []options.Endpoint{
+		{Path: "/readyz", Role: options.EndpointRoleReadiness, Checks: []string{app}},
+		{Path: "/livez", Role: options.EndpointRoleLiveness, Checks: []string{app}},
+		{Path: "/slow", Role: options.EndpointRoleLiveness, Checks: []string{slowScript}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- StartHttpServerWithContext(ctx, opts)
+	}()
+
+	baseUrl := "http://" + listener
+	assert.Eventually(t, func() bool {
+		status, _, err := getStatusForTest(baseUrl + "/livez")
+		return err == nil && status == http.StatusOK
+	}, 5*time.Second, 50*time.Millisecond, "Server did not start")
+
+	// A pass that is in flight when the shutdown starts must be allowed to complete
+	slowStatus := make(chan int, 1)
+	go func() {
+		status, _, err := getStatusForTest(baseUrl + "/slow")
+		assert.NoError(t, err)
+		slowStatus <- status
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	cancel()
+	time.Sleep(200 * time.Millisecond)
+
+	status, _, err := getStatusForTest(baseUrl + "/livez")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status, "Liveness endpoints must keep passing while draining")
+
+	status, body, err := getStatusForTest(baseUrl + "/readyz")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusGatewayTimeout, status)
+	assert.Equal(t, "draining", body)
+
+	select {
+	case err := <-serverErr:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		assert.FailNow(t, "Server did not shut down")
+	}
+	assert.Equal(t, http.StatusOK, <-slowStatus)
+
+	_, _, err = getStatusForTest(baseUrl + "/livez")
+	assert.Error(t, err, "The listener must be closed after shutting down")
+}
+
+func getStatusForTest(url string) (int, string, error) {
+	client := &http.Client{Timeout: 10 * time.Second, Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), err
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// serverState holds what outlives a single runChecks pass: the latest result of every check, keyed by check name, how
+// many times in a row each check has passed or failed, and the metrics exported on /metrics. It is shared by all
+// endpoints of a server and written to both by on-request passes and the background scheduler.
+type serverState struct {
+	mu      sync.RWMutex
+	checks  map[string]checkState
+	metrics *metrics
+	// draining is set once the server starts shutting down
+	draining atomic.Bool
+	// maintenance is set while maintenance mode was turned on manually, with SIGUSR1 or the admin endpoint
+	maintenance atomic.Bool
+}
+
+// checkState is the latest known outcome of a single check.
+type checkState struct {
+	result    CheckResult
+	checkedAt time.Time
+	// countedAt is when the latest run that counted towards the consecutive failures or successes started
+	countedAt            time.Time
+	healthy              bool
+	consecutiveFailures  int
+	consecutiveSuccesses int
+}
+
+func newServerState() *serverState {
+	return &serverState{checks: map[string]checkState{}, metrics: newMetrics()}
+}
+
+// record stores the result of a run of the given check and returns it with its status damped by the check's
+// thresholds: a healthy check is only reported as failing after failureThreshold consecutive failures, and an
+// unhealthy check only as passing again after successThreshold consecutive successes. The very first result of a
+// check is taken as is, since there is no history to damp. The error of the latest run is always kept, so that a
+// failure absorbed by the threshold is still visible. Canceled results say nothing about the health of a check, so
+// they are returned untouched and don't replace what is already known.
+//
+// Runs with the same outcome only extend a streak if they started at least half the check's interval after the latest
+// run that did. Several endpoints or load balancers requesting the check at once therefore count as a single run, and a
+// threshold of N takes N intervals to reach, however many requests trigger the check.
+func (state *serverState) record(c check, result CheckResult, checkedAt time.Time) CheckResult {
+	if result.Status == CheckStatusCanceled {
+		return result
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	previous, known := state.checks[c.name]
+	current := checkState{
+		checkedAt:            checkedAt,
+		countedAt:            previous.countedAt,
+		healthy:              previous.healthy,
+		consecutiveFailures:  previous.consecutiveFailures,
+		consecutiveSuccesses: previous.consecutiveSuccesses,
+	}
+	startedAt := checkedAt.Add(-result.elapsed)
+	spaced := !known || startedAt.Sub(previous.countedAt) >= c.interval/2
+
+	// A warning doesn't make the check unhealthy, it only degrades the endpoint
+	passed := result.Status == CheckStatusPassing || result.Status == CheckStatusWarning
+	if passed {
+		// A success always counts when it breaks a streak of failures
+		if spaced || previous.consecutiveSuccesses == 0 {
+			current.consecutiveSuccesses++
+			current.consecutiveFailures = 0
+			current.countedAt = startedAt
+		}
+		if !known || current.consecutiveSuccesses >= c.successThreshold {
+			current.healthy = true
+		}
+	} else {
+		if spaced || previous.consecutiveFailures == 0 {
+			current.consecutiveFailures++
+			current.consecutiveSuccesses = 0
+			current.countedAt = startedAt
+		}
+		if !known || current.consecutiveFailures >= c.failureThreshold {
+			current.healthy = false
+		}
+	}
+
+	if current.healthy {
+		if !passed {
+			result.Status = CheckStatusPassing
+		}
+	} else {
+		result.Status = CheckStatusFailing
+		if result.message == "" {
+			result.message = fmt.Sprintf("%s is recovering: passed %d of %d consecutive times required", c.description, current.consecutiveSuccesses, c.successThreshold)
+		}
+	}
+	result.ConsecutiveFailures = current.consecutiveFailures
+	result.ConsecutiveSuccesses = current.consecutiveSuccesses
+
+	current.result = result
+	state.checks[c.name] = current
+	state.metrics.observeCheck(c, result, !passed, current.healthy)
+	return result
+}
+
+// latest returns the latest known outcome of the named check, if it has completed at least once.
+func (state *serverState) latest(name string) (checkState, bool) {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+
+	stored, ok := state.checks[name]
+	return stored, ok
+}
+
+// startDraining marks the server as shutting down, which makes readiness endpoints fail.
+func (state *serverState) startDraining() {
+	state.draining.Store(true)
+}
+
+// isDraining reports whether the server is shutting down.
+func (state *serverState) isDraining() bool {
+	return state.draining.Load()
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/gruntwork-io/health-checker/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerStateThresholds(t *testing.T) {
+	c := check{name: "app", description: "TCP connection to 8080", failureThreshold: 3, successThreshold: 2}
+
+	passing := CheckResult{Name: "app", Status: CheckStatusPassing}
+	failing := CheckResult{Name: "app", Status: CheckStatusFailing, Error: "connection refused", message: "TCP connection to 8080 failed: connection refused"}
+	canceled := CheckResult{Name: "app", Status: CheckStatusCanceled}
+
+	testCases := []struct {
+		name           string
+		result         CheckResult
+		expectedStatus string
+	}{
+		{"first result is taken as is", passing, CheckStatusPassing},
+		{"first failure is absorbed", failing, CheckStatusPassing},
+		{"second failure is absorbed", failing, CheckStatusPassing},
+		{"canceled run changes nothing", canceled, CheckStatusCanceled},
+		{"third failure flips to failing", failing, CheckStatusFailing},
+		{"first success is not enough to recover", passing, CheckStatusFailing},
+		{"failure resets the success streak", failing, CheckStatusFailing},
+		{"first success again", passing, CheckStatusFailing},
+		{"second success recovers", passing, CheckStatusPassing},
+	}
+
+	state := newServerState()
+	for _, testCase := range testCases {
+		actual := state.record(c, testCase.result, time.Now())
+		assert.Equal(t, testCase.expectedStatus, actual.Status, testCase.name)
+	}
+
+	stored, ok := state.latest("app")
+	assert.True(t, ok)
+	assert.Equal(t, 2, stored.result.ConsecutiveSuccesses)
+	assert.Equal(t, 0, stored.result.ConsecutiveFailures)
+}
+
+func TestServerStateKeepsErrorOfAbsorbedFailure(t *testing.T) {
+	c := check{name: "app", description: "TCP connection to 8080", failureThreshold: 2, successThreshold: 1}
+	state := newServerState()
+
+	state.record(c, CheckResult{Name: "app", Status: CheckStatusPassing}, time.Now())
+	actual := state.record(c, CheckResult{Name: "app", Status: CheckStatusFailing, Error: "connection refused"}, time.Now())
+
+	assert.Equal(t, CheckStatusPassing, actual.Status)
+	assert.Equal(t, "connection refused", actual.Error)
+	assert.Equal(t, 1, actual.ConsecutiveFailures)
+}
+
+func TestServerStateRecoveringMessage(t *testing.T) {
+	c := check{name: "app", description: "TCP connection to 8080", failureThreshold: 1, successThreshold: 3}
+	state := newServerState()
+
+	state.record(c, CheckResult{Name: "app", Status: CheckStatusFailing, message: "TCP connection to 8080 failed"}, time.Now())
+	actual := state.record(c, CheckResult{Name: "app", Status: CheckStatusPassing}, time.Now())
+
+	assert.Equal(t, CheckStatusFailing, actual.Status)
+	assert.Equal(t, "TCP connection to 8080 is recovering: passed 1 of 3 consecutive times required", actual.message)
+}
+
+func TestServerStateCountsRunsOncePerInterval(t *testing.T) {
+	c := check{name: "app", description: "TCP connection to 8080", interval: 10 * time.Second, failureThreshold: 2, successThreshold: 2}
+	passing := CheckResult{Name: "app", Status: CheckStatusPassing}
+	failing := CheckResult{Name: "app", Status: CheckStatusFailing, Error: "connection refused"}
+
+	start := time.Now()
+	state := newServerState()
+	state.record(c, passing, start)
+
+	// /livez and /readyz run the check at once: only one of the failures counts
+	actual := state.record(c, failing, start.Add(10*time.Second))
+	assert.Equal(t, CheckStatusPassing, actual.Status)
+	actual = state.record(c, failing, start.Add(10*time.Second+time.Millisecond))
+	assert.Equal(t, CheckStatusPassing, actual.Status)
+	assert.Equal(t, 1, actual.ConsecutiveFailures)
+	assert.Equal(t, "connection refused", actual.Error)
+
+	// A second load balancer polling shortly after doesn't count either
+	actual = state.record(c, failing, start.Add(12*time.Second))
+	assert.Equal(t, CheckStatusPassing, actual.Status)
+	assert.Equal(t, 1, actual.ConsecutiveFailures)
+
+	// The next interval does
+	actual = state.record(c, failing, start.Add(20*time.Second))
+	assert.Equal(t, CheckStatusFailing, actual.Status)
+	assert.Equal(t, 2, actual.ConsecutiveFailures)
+
+	// A success breaking the streak always counts, but the ones right after it don't
+	actual = state.record(c, passing, start.Add(21*time.Second))
+	assert.Equal(t, 1, actual.ConsecutiveSuccesses)
+	assert.Equal(t, 0, actual.ConsecutiveFailures)
+	actual = state.record(c, passing, start.Add(22*time.Second))
+	assert.Equal(t, CheckStatusFailing, actual.Status)
+	assert.Equal(t, 1, actual.ConsecutiveSuccesses)
+}
+
+func TestSharedCheckCountsOnceAcrossEndpoints(t *testing.T) {
+	ports, err := test.GetFreePorts(1)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+	address := test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0])
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	go handleRequests(t, l, nil)
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", []string{address})
+	opts.Ports[0].FailureThreshold = 2
+	opts.Ports[0].Interval = 10
+	liveness := options.Endpoint{Path: "/livez"}
+	readiness := options.Endpoint{Path: "/readyz"}
+	state := newServerState()
+
+	assert.Equal(t, CheckStatusPassing, runChecks(opts, liveness, state).results[0].Status)
+	closeListeners(t, []net.Listener{l})
+
+	// Both endpoints see the failure, but it is a single failure of the check
+	assert.Equal(t, CheckStatusPassing, runChecks(opts, liveness, state).results[0].Status)
+	result := runChecks(opts, readiness, state).results[0]
+	assert.Equal(t, CheckStatusPassing, result.Status)
+	assert.Equal(t, 1, result.ConsecutiveFailures)
+}
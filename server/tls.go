@@ -0,0 +1,149 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/sirupsen/logrus"
+)
+
+// tlsReloader serves the listener's certificate and client CA bundle, reloading them from disk whenever one of the
+// files changes so that rotated certificates are picked up without a restart. Files are checked on every handshake;
+// stat'ing a few files is cheap compared to the handshake itself.
+type tlsReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	logger       *logrus.Logger
+
+	mu        sync.Mutex
+	config    *tls.Config
+	modTimes  map[string]time.Time
+	lastError string
+}
+
+// newTlsReloader loads the listener's TLS files for the first time. Unlike later reloads, failing to load them is an
+// error, since there is no previous certificate to fall back to.
+func newTlsReloader(opts *options.Options) (*tlsReloader, error) {
+	reloader := &tlsReloader{
+		certFile:     opts.TLSCert,
+		keyFile:      opts.TLSKey,
+		clientCAFile: opts.TLSClientCA,
+		logger:       opts.Logger,
+	}
+
+	modTimes, err := reloader.statFiles()
+	if err != nil {
+		return nil, err
+	}
+	config, err := reloader.load()
+	if err != nil {
+		return nil, err
+	}
+
+	reloader.config = config
+	reloader.modTimes = modTimes
+	return reloader, nil
+}
+
+// tlsConfig returns the server TLS configuration, which resolves the current certificate and client CAs per connection.
+func (reloader *tlsReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return reloader.current(), nil
+		},
+	}
+}
+
+// current returns the TLS configuration for a new connection, reloading the files first if any of them changed. If a
+// reload fails, for example because the certificate was replaced before its key, the previous configuration is kept.
+func (reloader *tlsReloader) current() *tls.Config {
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+
+	modTimes, err := reloader.statFiles()
+	if err == nil && !sameModTimes(modTimes, reloader.modTimes) {
+		var config *tls.Config
+		config, err = reloader.load()
+		if err == nil {
+			reloader.logger.Infof("Reloaded TLS certificate %s", reloader.certFile)
+			reloader.config = config
+			reloader.modTimes = modTimes
+		}
+	}
+
+	if err != nil {
+		// Only log each distinct failure once, rather than on every handshake
+		if err.Error() != reloader.lastError {
+			reloader.logger.Warnf("Failed to reload TLS files, keeping the previous certificate: %v", err)
+		}
+		reloader.lastError = err.Error()
+	} else {
+		reloader.lastError = ""
+	}
+
+	return reloader.config
+}
+
+// load reads the certificate, key and client CA bundle and builds the TLS configuration from them.
+func (reloader *tlsReloader) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(reloader.certFile, reloader.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate %s and key %s: %w", reloader.certFile, reloader.keyFile, err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if reloader.clientCAFile != "" {
+		pem, err := os.ReadFile(reloader.clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS client CA %s: %w", reloader.clientCAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM encoded certificate found in TLS client CA %s", reloader.clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+// statFiles returns the modification time of every TLS file in use. Stat follows symlinks, so that the atomic symlink
+// swap used by Kubernetes to update mounted secrets is detected as well.
+func (reloader *tlsReloader) statFiles() (map[string]time.Time, error) {
+	modTimes := map[string]time.Time{}
+	for _, file := range []string{reloader.certFile, reloader.keyFile, reloader.clientCAFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat TLS file %s: %w", file, err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+func sameModTimes(a map[string]time.Time, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, modTime := range a {
+		if !modTime.Equal(b[file]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/gruntwork-io/health-checker/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTlsListener(t *testing.T) {
+	tmpDir := t.TempDir()
+	ca, caKey := createCertificateForTest(t, "test-ca", nil, nil)
+	serverCert := filepath.Join(tmpDir, "server.crt")
+	serverKey := filepath.Join(tmpDir, "server.key")
+	writeCertificateForTest(t, "server-1", ca, caKey, serverCert, serverKey)
+	clientCA := filepath.Join(tmpDir, "client-ca.crt")
+	writePem(t, clientCA, "CERTIFICATE", ca.Raw)
+	clientCert := filepath.Join(tmpDir, "client.crt")
+	clientKey := filepath.Join(tmpDir, "client.key")
+	writeCertificateForTest(t, "client", ca, caKey, clientCert, clientKey)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	t.Run("serves the certificate", func(t *testing.T) {
+		url := startTlsServerForTest(t, &options.Options{TLSCert: serverCert, TLSKey: serverKey})
+
+		commonName, err := getForTest(url, roots, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "server-1", commonName)
+	})
+
+	t.Run("requires a client certificate", func(t *testing.T) {
+		url := startTlsServerForTest(t, &options.Options{TLSCert: serverCert, TLSKey: serverKey, TLSClientCA: clientCA})
+
+		_, err := getForTest(url, roots, nil)
+		assert.Error(t, err)
+
+		cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		assert.NoError(t, err)
+		_, err = getForTest(url, roots, &cert)
+		assert.NoError(t, err)
+	})
+
+	t.Run("reloads the certificate when it changes", func(t *testing.T) {
+		cert := filepath.Join(tmpDir, "reloaded.crt")
+		key := filepath.Join(tmpDir, "reloaded.key")
+		writeCertificateForTest(t, "server-1", ca, caKey, cert, key)
+		url := startTlsServerForTest(t, &options.Options{TLSCert: cert, TLSKey: key})
+
+		commonName, err := getForTest(url, roots, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "server-1", commonName)
+
+		// A broken certificate is ignored, and the previous one kept
+		assert.NoError(t, os.WriteFile(cert, []byte("not a certificate"), 0600))
+		touchForTest(t, time.Now().Add(time.Minute), cert)
+		commonName, err = getForTest(url, roots, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "server-1", commonName)
+
+		writeCertificateForTest(t, "server-2", ca, caKey, cert, key)
+		touchForTest(t, time.Now().Add(2*time.Minute), cert, key)
+		commonName, err = getForTest(url, roots, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "server-2", commonName)
+	})
+
+	t.Run("fails on missing files", func(t *testing.T) {
+		_, err := newTlsReloader(&options.Options{TLSCert: filepath.Join(tmpDir, "missing.crt"), TLSKey: serverKey, Logger: createOptionsForTest(t, 5, nil, nil, "", nil).Logger})
+		assert.ErrorContains(t, err, "missing.crt")
+	})
+}
+
+// startTlsServerForTest serves a trivial handler with the TLS settings from opts, returning its URL.
+func startTlsServerForTest(t *testing.T, opts *options.Options) string {
+	opts.Logger = createOptionsForTest(t, 5, nil, nil, "", nil).Logger
+	reloader, err := newTlsReloader(opts)
+	if err != nil {
+		assert.FailNow(t, "Failed to load TLS files: %s", err.Error())
+	}
+
+	l, err := net.Listen("tcp", test.ListenerString("127.0.0.1", 0))
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	})}
+	go func() {
+		_ = srv.Serve(tls.NewListener(l, reloader.tlsConfig()))
+	}()
+	t.Cleanup(func() {
+		_ = srv.Close()
+	})
+
+	return "https://" + l.Addr().String()
+}
+
+// getForTest performs a request on a fresh connection and returns the common name of the server certificate.
+func getForTest(url string, roots *x509.CertPool, clientCert *tls.Certificate) (string, error) {
+	tlsConfig := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+	if clientCert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*clientCert}
+	}
+	client := &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{TLSClientConfig: tlsConfig, DisableKeepAlives: true}}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	return resp.TLS.PeerCertificates[0].Subject.CommonName, nil
+}
+
+// createCertificateForTest creates a certificate for localhost with the given common name, signed by the given CA, or
+// a self-signed CA if parent is nil.
+func createCertificateForTest(t *testing.T, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent = template
+		parentKey = key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return cert, key
+}
+
+func writeCertificateForTest(t *testing.T, commonName string, ca *x509.Certificate, caKey *ecdsa.PrivateKey, certFile string, keyFile string) {
+	cert, key := createCertificateForTest(t, commonName, ca, caKey)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	writePem(t, certFile, "CERTIFICATE", cert.Raw)
+	writePem(t, keyFile, "EC PRIVATE KEY", keyDer)
+}
+
+func writePem(t *testing.T, file string, blockType string, der []byte) {
+	err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)
+	assert.NoError(t, err)
+}
+
+// touchForTest sets the modification time of the given files, so that a change is detected even on file systems with a
+// coarse timestamp resolution.
+func touchForTest(t *testing.T, modTime time.Time, files ...string) {
+	for _, file := range files {
+		assert.NoError(t, os.Chtimes(file, modTime, modTime))
+	}
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// attemptTlsVerification fetches the certificate chain from the check's address or file, verifies it, and checks how
+// long the leaf certificate remains valid. The expiry date is reported in the output and as metrics, even if the chain
+// doesn't verify.
+func attemptTlsVerification(ctx context.Context, tlsCheck options.TlsCheck, opts *options.Options) checkOutcome {
+	certs, serverName, err := fetchCertificates(ctx, tlsCheck, opts)
+	if err != nil {
+		return checkOutcome{err: err}
+	}
+
+	leaf := certs[0]
+	remaining := time.Until(leaf.NotAfter)
+	days := int(remaining.Hours() / 24)
+	outcome := checkOutcome{
+		output: fmt.Sprintf("%s issued by %s expires %s (in %d days)", leaf.Subject, leaf.Issuer, leaf.NotAfter.UTC().Format(time.RFC3339), days),
+		metrics: []Metric{
+			{Name: "not_after", Value: float64(leaf.NotAfter.Unix()), Unit: "s"},
+			{Name: "remaining", Value: remaining.Round(time.Second).Seconds(), Unit: "s"},
+		},
+	}
+
+	roots, err := loadCertPool(tlsCheck.CAFile)
+	if err != nil {
+		outcome.err = err
+		return outcome
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{DNSName: serverName, Roots: roots, Intermediates: intermediates})
+	if err != nil {
+		outcome.err = err
+		return outcome
+	}
+
+	switch {
+	case withinDays(remaining, tlsCheck.ExpiryCriticalDays):
+		outcome.err = fmt.Errorf("certificate expires in %d days, within the critical window of %d days", days, tlsCheck.ExpiryCriticalDays)
+	case withinDays(remaining, tlsCheck.ExpiryWarningDays):
+		outcome.err = fmt.Errorf("certificate expires in %d days, within the warning window of %d days", days, tlsCheck.ExpiryWarningDays)
+		outcome.warning = true
+	}
+	return outcome
+}
+
+// fetchCertificates returns the certificate chain to verify, leaf first, and the name to verify it for. The chain is
+// read from the check's file, or presented by the server at its address during a handshake with the check's SNI.
+func fetchCertificates(ctx context.Context, tlsCheck options.TlsCheck, opts *options.Options) ([]*x509.Certificate, string, error) {
+	logger := opts.Logger
+
+	if tlsCheck.CertFile != "" {
+		logger.Infof("Reading the TLS certificate %s...", tlsCheck.CertFile)
+		certs, err := readCertificates(tlsCheck.CertFile)
+		return certs, tlsCheck.ServerName, err
+	}
+
+	address := tlsCheck.Address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "443")
+	}
+	serverName := tlsCheck.ServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(address)
+	}
+	logger.Infof("Fetching the TLS certificate of %s (%s)...", address, serverName)
+
+	dialer := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: tlsTimeout(tlsCheck, opts)},
+		// The chain is verified by the caller, which can then report the expiry date of a certificate that doesn't
+		// verify
+		/* #nosec G402 */
+		Config: &tls.Config{ServerName: serverName, InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, "", fmt.Errorf("%s presented no certificate", address)
+	}
+	return certs, serverName, nil
+}
+
+// readCertificates parses all certificates in the given PEM file.
+func readCertificates(file string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate in %s: %w", file, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM encoded certificate found in %s", file)
+	}
+	return certs, nil
+}
+
+// loadCertPool returns the pool of the CAs in the given PEM file, or nil for the system roots if no file is given.
+func loadCertPool(file string) (*x509.CertPool, error) {
+	if file == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("no PEM encoded certificate found in " + file)
+	}
+	return pool, nil
+}
+
+// withinDays reports whether the remaining validity is within a window of the given number of days. A window of 0
+// days is disabled.
+func withinDays(remaining time.Duration, days int) bool {
+	return days > 0 && remaining < time.Duration(days)*24*time.Hour
+}
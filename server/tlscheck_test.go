@@ -0,0 +1,107 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTlsChecks(t *testing.T) {
+	tmpDir := t.TempDir()
+	ca, caKey := createCertificateForTest(t, "test-ca", nil, nil)
+	otherCa, _ := createCertificateForTest(t, "other-ca", nil, nil)
+	caFile := filepath.Join(tmpDir, "ca.crt")
+	otherCaFile := filepath.Join(tmpDir, "other-ca.crt")
+	certFile := filepath.Join(tmpDir, "server.crt")
+	writePem(t, caFile, "CERTIFICATE", ca.Raw)
+	writePem(t, otherCaFile, "CERTIFICATE", otherCa.Raw)
+	writeCertificateForTest(t, "server", ca, caKey, certFile, filepath.Join(tmpDir, "server.key"))
+
+	address := startTlsCertificateServerForTest(t, certFile, filepath.Join(tmpDir, "server.key"))
+	_, port, err := net.SplitHostPort(address)
+	assert.NoError(t, err)
+	localhost := net.JoinHostPort("localhost", port)
+
+	testCases := []struct {
+		name            string
+		check           options.TlsCheck
+		expectedError   string
+		expectedWarning bool
+	}{
+		{"address", options.TlsCheck{Address: localhost, CAFile: caFile}, "", false},
+		{"address with server name", options.TlsCheck{Address: address, ServerName: "localhost", CAFile: caFile}, "", false},
+		{"file", options.TlsCheck{CertFile: certFile, CAFile: caFile}, "", false},
+		{"file with server name", options.TlsCheck{CertFile: certFile, ServerName: "localhost", CAFile: caFile}, "", false},
+		{"warning window", options.TlsCheck{Address: localhost, CAFile: caFile, ExpiryWarningDays: 1}, "within the warning window of 1 days", true},
+		{"critical window", options.TlsCheck{CertFile: certFile, CAFile: caFile, ExpiryWarningDays: 30, ExpiryCriticalDays: 1}, "within the critical window of 1 days", false},
+		{"untrusted CA", options.TlsCheck{Address: localhost, CAFile: otherCaFile}, "certificate signed by unknown authority", false},
+		{"system roots", options.TlsCheck{CertFile: certFile}, "certificate signed by unknown authority", false},
+		{"wrong server name", options.TlsCheck{Address: address, ServerName: "example.com", CAFile: caFile}, "not example.com", false},
+		{"missing file", options.TlsCheck{CertFile: filepath.Join(tmpDir, "missing.crt")}, "no such file or directory", false},
+		{"not a certificate", options.TlsCheck{CertFile: filepath.Join(tmpDir, "server.key"), CAFile: caFile}, "no PEM encoded certificate found", false},
+	}
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			outcome := attemptTlsVerification(t.Context(), testCase.check, opts)
+			if testCase.expectedError == "" {
+				assert.NoError(t, outcome.err)
+			} else {
+				assert.ErrorContains(t, outcome.err, testCase.expectedError)
+			}
+			assert.Equal(t, testCase.expectedWarning, outcome.warning)
+		})
+	}
+}
+
+func TestTlsCheckResult(t *testing.T) {
+	tmpDir := t.TempDir()
+	ca, caKey := createCertificateForTest(t, "test-ca", nil, nil)
+	caFile := filepath.Join(tmpDir, "ca.crt")
+	certFile := filepath.Join(tmpDir, "server.crt")
+	writePem(t, caFile, "CERTIFICATE", ca.Raw)
+	writeCertificateForTest(t, "server", ca, caKey, certFile, filepath.Join(tmpDir, "server.key"))
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+	opts.TlsChecks = []options.TlsCheck{{CheckOptions: options.CheckOptions{CheckName: "server-cert"}, CertFile: certFile, CAFile: caFile, ExpiryWarningDays: 14}}
+
+	pass := runChecks(opts, options.Endpoint{Path: "/"}, newServerState())
+	assert.Len(t, pass.results, 1)
+	assert.Equal(t, "server-cert", pass.results[0].Name)
+	assert.Equal(t, options.CheckTypeTls, pass.results[0].Type)
+	assert.Equal(t, CheckStatusWarning, pass.results[0].Status)
+	assert.Contains(t, pass.results[0].Output, "CN=server issued by CN=test-ca expires")
+	assert.Len(t, pass.results[0].Metrics, 2)
+	assert.Equal(t, "not_after", pass.results[0].Metrics[0].Name)
+	assert.Equal(t, "remaining", pass.results[0].Metrics[1].Name)
+	assert.InDelta(t, 3600, pass.results[0].Metrics[1].Value, 60)
+}
+
+// startTlsCertificateServerForTest accepts TLS connections with the given certificate until the test ends, completing
+// the handshake and closing them, and returns its address.
+func startTlsCertificateServerForTest(t *testing.T, certFile string, keyFile string) string {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	assert.NoError(t, err)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		assert.FailNow(t, "Failed to start TLS server: %s", err.Error())
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// maxDatagramSize is the largest payload a UDP datagram can carry.
+const maxDatagramSize = 65535
+
+// attemptUdpProbe sends the check's payload to its address and, if the check expects a response, waits for a single
+// datagram in return and verifies it. The response is returned as the output, so that it shows up in the detailed
+// status. Without an expected response the check can only fail if the datagram can't be sent, since UDP doesn't
+// acknowledge delivery.
+func attemptUdpProbe(ctx context.Context, udpCheck options.UdpCheck, opts *options.Options) checkOutcome {
+	logger := opts.Logger
+
+	// If only a port is provided, default to 0.0.0.0
+	address := udpCheck.Address
+	if !strings.Contains(address, ":") {
+		address = fmt.Sprintf("0.0.0.0:%s", udpCheck.Address)
+	}
+	logger.Infof("Sending a datagram to %s via UDP...", address)
+
+	expect, err := compileProbeExpect(udpCheck.Probe)
+	if err != nil {
+		return checkOutcome{err: err}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, udpTimeout(udpCheck))
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return checkOutcome{err: err}
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// Unblock the read below as soon as the check times out or is canceled
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+
+	if _, err := conn.Write(udpCheck.Send); err != nil {
+		return checkOutcome{err: err}
+	}
+	if !udpCheck.AwaitsResponse() {
+		return checkOutcome{}
+	}
+
+	buffer := make([]byte, maxDatagramSize)
+	n, err := conn.Read(buffer)
+	if ctx.Err() != nil {
+		return checkOutcome{err: fmt.Errorf("no response from %s within %v", address, udpTimeout(udpCheck))}
+	}
+	if err != nil {
+		return checkOutcome{err: fmt.Errorf("no response from %s: %w", address, err)}
+	}
+
+	response := buffer[:n]
+	return checkOutcome{output: describePayload(response), err: verifyProbeResponse(udpCheck.Probe, expect, response)}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"net"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUdpChecks(t *testing.T) {
+	echo := startUdpServerForTest(t, func(request []byte) []byte { return request })
+	silent := startUdpServerForTest(t, func(request []byte) []byte { return nil })
+
+	// A port that was just released is very likely closed
+	closedConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	closed := closedConn.LocalAddr().String()
+	_ = closedConn.Close()
+
+	testCases := []struct {
+		name           string
+		check          options.UdpCheck
+		expectedError  string
+		expectedOutput string
+	}{
+		{"send only", options.UdpCheck{Address: echo, Probe: options.Probe{Send: []byte("ping")}}, "", ""},
+		{"send only to a silent server", options.UdpCheck{Address: silent, Probe: options.Probe{Send: []byte("ping")}}, "", ""},
+		{"expected text", options.UdpCheck{Address: echo, Probe: options.Probe{Send: []byte("PING 42"), Expect: `^PING \d+$`}}, "", `"PING 42"`},
+		{"expected bytes", options.UdpCheck{Address: echo, Probe: options.Probe{Send: []byte{0xde, 0xad, 0xbe, 0xef}, ExpectBytes: []byte{0xbe, 0xef}}}, "", "0xdeadbeef"},
+		{"unexpected text", options.UdpCheck{Address: echo, Probe: options.Probe{Send: []byte("PONG"), Expect: "^PING"}}, `response "PONG" doesn't match ^PING`, `"PONG"`},
+		{"unexpected bytes", options.UdpCheck{Address: echo, Probe: options.Probe{Send: []byte{0xde, 0xad}, ExpectBytes: []byte{0xbe, 0xef}}}, "response 0xdead doesn't contain beef", "0xdead"},
+		{"no response", options.UdpCheck{CheckOptions: options.CheckOptions{Timeout: 1}, Address: silent, Probe: options.Probe{Send: []byte("ping"), Expect: "pong"}}, "no response from " + silent, ""},
+		{"closed port", options.UdpCheck{CheckOptions: options.CheckOptions{Timeout: 1}, Address: closed, Probe: options.Probe{Send: []byte("ping"), Expect: "pong"}}, "no response from " + closed, ""},
+	}
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			outcome := attemptUdpProbe(t.Context(), testCase.check, opts)
+			if testCase.expectedError == "" {
+				assert.NoError(t, outcome.err)
+			} else {
+				assert.ErrorContains(t, outcome.err, testCase.expectedError)
+			}
+			assert.Equal(t, testCase.expectedOutput, outcome.output)
+		})
+	}
+}
+
+func TestUdpCheckResult(t *testing.T) {
+	echo := startUdpServerForTest(t, func(request []byte) []byte { return request })
+
+	opts := createOptionsForTest(t, 5, nil, nil, "", nil)
+	opts.UdpChecks = []options.UdpCheck{{CheckOptions: options.CheckOptions{CheckName: "statsd"}, Address: echo, Probe: options.Probe{Send: []byte("ping"), Expect: "ping"}}}
+
+	pass := runChecks(opts, options.Endpoint{Path: "/"}, newServerState())
+	assert.Len(t, pass.results, 1)
+	assert.Equal(t, "statsd", pass.results[0].Name)
+	assert.Equal(t, options.CheckTypeUdp, pass.results[0].Type)
+	assert.Equal(t, CheckStatusPassing, pass.results[0].Status)
+	assert.Equal(t, `"ping"`, pass.results[0].Output)
+}
+
+// startUdpServerForTest answers every datagram with the response returned by the given handler until the test ends,
+// and returns its address. Datagrams for which the handler returns nil are not answered.
+func startUdpServerForTest(t *testing.T, handler func(request []byte) []byte) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		assert.FailNow(t, "Failed to start UDP server: %s", err.Error())
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buffer := make([]byte, maxDatagramSize)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if response := handler(buffer[:n]); response != nil {
+				_, _ = conn.WriteTo(response, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}